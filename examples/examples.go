@@ -10,3 +10,30 @@ var SystemManagerFidl []byte
 
 //go:embed FireAndForget.fidl
 var FireAndForgetsFidl []byte
+
+//go:embed Enumerations.fidl
+var EnumerationsFidl []byte
+
+//go:embed Maps.fidl
+var MapsFidl []byte
+
+//go:embed Unions.fidl
+var UnionsFidl []byte
+
+//go:embed Constants.fidl
+var ConstantsFidl []byte
+
+//go:embed TypeCollections.fidl
+var TypeCollectionsFidl []byte
+
+//go:embed CommonTypes.fidl
+var CommonTypesFidl []byte
+
+//go:embed Imports.fidl
+var ImportsFidl []byte
+
+//go:embed Inheritance.fidl
+var InheritanceFidl []byte
+
+//go:embed MethodErrors.fidl
+var MethodErrorsFidl []byte
@@ -4,9 +4,219 @@ package notification
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/godbus/dbus/v5"
+	"strings"
+	"sync"
 )
 
+// emitWithDestination sends the signal name (interface and member) only to the
+// bus name destination instead of broadcasting it.
+func emitWithDestination(conn *dbus.Conn, path dbus.ObjectPath, name, destination string, values ...interface{}) error {
+	separator := strings.LastIndex(name, ".")
+	if separator == -1 {
+		return fmt.Errorf("invalid signal name %s", name)
+	}
+
+	msg := &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:        dbus.MakeVariant(path),
+			dbus.FieldInterface:   dbus.MakeVariant(name[:separator]),
+			dbus.FieldMember:      dbus.MakeVariant(name[separator+1:]),
+			dbus.FieldDestination: dbus.MakeVariant(destination),
+		},
+		Body: values,
+	}
+	if len(values) > 0 {
+		msg.Headers[dbus.FieldSignature] = dbus.MakeVariant(dbus.SignatureOf(values...))
+	}
+
+	if err := msg.IsValid(); err != nil {
+		return err
+	}
+
+	return conn.Send(msg, nil).Err
+}
+
+// SignalPolicy decides what happens to a signal when the buffer of a listener
+// is full.
+type SignalPolicy int
+
+const (
+	// SignalBlock waits until the listener took the signal. Until then no
+	// other signal of the connection is dispatched.
+	SignalBlock SignalPolicy = iota
+	// SignalDrop drops the signal.
+	SignalDrop
+)
+
+// ListenOption configures a listener returned by the ListenFor and Watch methods.
+type ListenOption func(*signalSubscriber)
+
+// WithSignalBuffer sets the number of signals buffered for a listener (default 10).
+func WithSignalBuffer(size int) ListenOption {
+	return func(subscriber *signalSubscriber) {
+		subscriber.bufferSize = size
+	}
+}
+
+// WithSignalPolicy sets what happens when the buffer of a listener is full
+// (default SignalBlock).
+func WithSignalPolicy(policy SignalPolicy) ListenOption {
+	return func(subscriber *signalSubscriber) {
+		subscriber.policy = policy
+	}
+}
+
+// signalKey identifies the signals a subscriber is interested in.
+type signalKey struct {
+	path dbus.ObjectPath
+	name string
+}
+
+type signalSubscriber struct {
+	bufferSize int
+	policy     SignalPolicy
+	keys       []signalKey
+	signals    chan *dbus.Signal
+	done       chan struct{}
+}
+
+// signalDispatcher receives all signals of a connection and routes them to
+// the subscribers registered for their path, interface and member.
+type signalDispatcher struct {
+	lock        sync.RWMutex
+	subscribers map[signalKey]map[*signalSubscriber]struct{}
+	signals     chan *dbus.Signal
+	// closed is closed when the connection is closed.
+	closed chan struct{}
+}
+
+var (
+	signalDispatchersLock sync.Mutex
+	signalDispatchers     = map[*dbus.Conn]*signalDispatcher{}
+)
+
+// signalDispatcherOf returns the dispatcher of the connection and starts it on first use.
+func signalDispatcherOf(conn *dbus.Conn) *signalDispatcher {
+	signalDispatchersLock.Lock()
+	defer signalDispatchersLock.Unlock()
+
+	if dispatcher, ok := signalDispatchers[conn]; ok {
+		return dispatcher
+	}
+
+	dispatcher := &signalDispatcher{
+		subscribers: map[signalKey]map[*signalSubscriber]struct{}{},
+		signals:     make(chan *dbus.Signal, 10),
+		closed:      make(chan struct{}),
+	}
+	signalDispatchers[conn] = dispatcher
+	conn.Signal(dispatcher.signals)
+
+	go func() {
+		// the channel is closed by godbus when the connection is closed
+		for sig := range dispatcher.signals {
+			dispatcher.dispatch(sig)
+		}
+
+		signalDispatchersLock.Lock()
+		delete(signalDispatchers, conn)
+		signalDispatchersLock.Unlock()
+		close(dispatcher.closed)
+	}()
+
+	return dispatcher
+}
+
+func (dispatcher *signalDispatcher) subscribe(keys []signalKey, options ...ListenOption) *signalSubscriber {
+	subscriber := &signalSubscriber{bufferSize: 10, policy: SignalBlock, keys: keys, done: make(chan struct{})}
+	for _, option := range options {
+		option(subscriber)
+	}
+	subscriber.signals = make(chan *dbus.Signal, subscriber.bufferSize)
+
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	for _, key := range keys {
+		if dispatcher.subscribers[key] == nil {
+			dispatcher.subscribers[key] = map[*signalSubscriber]struct{}{}
+		}
+		dispatcher.subscribers[key][subscriber] = struct{}{}
+	}
+
+	return subscriber
+}
+
+func (dispatcher *signalDispatcher) unsubscribe(subscriber *signalSubscriber) {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	for _, key := range subscriber.keys {
+		delete(dispatcher.subscribers[key], subscriber)
+		if len(dispatcher.subscribers[key]) == 0 {
+			delete(dispatcher.subscribers, key)
+		}
+	}
+
+	// the signals channel is never closed, done stops a blocked dispatch instead
+	close(subscriber.done)
+}
+
+func (dispatcher *signalDispatcher) dispatch(sig *dbus.Signal) {
+	dispatcher.lock.RLock()
+	subscribers := make([]*signalSubscriber, 0, len(dispatcher.subscribers[signalKey{sig.Path, sig.Name}]))
+	for subscriber := range dispatcher.subscribers[signalKey{sig.Path, sig.Name}] {
+		subscribers = append(subscribers, subscriber)
+	}
+	dispatcher.lock.RUnlock()
+
+	for _, subscriber := range subscribers {
+		if subscriber.policy == SignalDrop {
+			select {
+			case subscriber.signals <- sig:
+			case <-subscriber.done:
+			default:
+			}
+			continue
+		}
+
+		select {
+		case subscriber.signals <- sig:
+		case <-subscriber.done:
+		}
+	}
+}
+
+// nameOwnerMatchOptions matches the NameOwnerChanged signals of the bus name.
+func nameOwnerMatchOptions(name string) []dbus.MatchOption {
+	return []dbus.MatchOption{
+		dbus.WithMatchSender("org.freedesktop.DBus"),
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, name),
+	}
+}
+
+// nameOwner returns the unique name currently owning the bus name or an empty
+// string if nobody owns it.
+func nameOwner(ctx context.Context, conn *dbus.Conn, name string) (string, error) {
+	var owner string
+	err := conn.BusObject().
+		CallWithContext(ctx, "org.freedesktop.DBus.GetNameOwner", 0, name).
+		Store(&owner)
+
+	var dbusError dbus.Error
+	if errors.As(err, &dbusError) && dbusError.Name == "org.freedesktop.DBus.Error.NameHasNoOwner" {
+		return "", nil
+	}
+
+	return owner, err
+}
+
 type NotificationsSender interface {
 	Notify(ctx context.Context, app_name string, replaces_id uint32, app_icon string, summary string, body string, actions []string, hints Hint, expire_timeout int32) (uint8, error)
 
@@ -30,6 +240,7 @@ func NewNotificationsSender(dest, path string) (*notificationsSender, error) {
 		destination:           dest,
 		path:                  dbus.ObjectPath(path),
 		broadcastMatchOptions: broadcastMatchOptions,
+		ownerMatchOptions:     nameOwnerMatchOptions(dest),
 	}, nil
 }
 
@@ -38,6 +249,7 @@ type notificationsSender struct {
 	destination           string
 	path                  dbus.ObjectPath
 	broadcastMatchOptions []dbus.MatchOption
+	ownerMatchOptions     []dbus.MatchOption
 }
 
 func (impl *notificationsSender) Close() error {
@@ -45,14 +257,12 @@ func (impl *notificationsSender) Close() error {
 }
 
 func (impl *notificationsSender) Notify(ctx context.Context, app_name string, replaces_id uint32, app_icon string, summary string, body string, actions []string, hints Hint, expire_timeout int32) (uint8, error) {
-
 	var result uint8
 
-	err := impl.dbusConnection.Object(impl.destination, impl.path).
-		CallWithContext(ctx, "org.freedesktop.Notifications.Notify", 0, app_name, replaces_id, app_icon, summary, body, actions, hints, expire_timeout).
-		Store(&result)
+	call := impl.dbusConnection.Object(impl.destination, impl.path).
+		CallWithContext(ctx, "org.freedesktop.Notifications.Notify", 0, app_name, replaces_id, app_icon, summary, body, actions, hints, expire_timeout)
 
-	if err != nil {
+	if err := call.Store(&result); err != nil {
 		return result, err
 	}
 
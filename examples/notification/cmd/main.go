@@ -7,7 +7,7 @@ import (
 	"log"
 )
 
-//go:generate go-fidl -sender -package notification -in ../../Notifications.fidl -out ../NotificationSender.go
+//go:generate go-fidl -sender -package notification -in ../../Notifications.fidl -type-map ../types.map -out ../NotificationSender.go
 
 func main() {
 
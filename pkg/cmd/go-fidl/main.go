@@ -2,23 +2,50 @@ package main
 
 import (
 	_ "embed"
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/SourceFellows/go-fidl-dbus-generator/pkg"
 	"github.com/alecthomas/repr"
 	"log"
 	"os"
+	"strings"
 )
 
+// stringList is a flag which can be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func main() {
 
 	inFile := flag.String("in", "", "path to FIDL file to parse")
 	outFile := flag.String("out", "", "path to generated file")
 
 	packageName := flag.String("package", "", "package to generate the result in")
+	enumType := flag.String("enum-type", "Int32", "FIDL integer type enumerations are marshalled as")
+
+	var includePaths stringList
+	flag.Var(&includePaths, "I", "directory to search imported FIDL files in (repeatable)")
+
+	var goPackages stringList
+	flag.Var(&goPackages, "M", "maps a FIDL package to a Go import path, optionally preceded by the package name, e.g. org.example.types=example.com/types or org.example.types=types=example.com/go-types (repeatable)")
+
+	typeMapFile := flag.String("type-map", "", "path to a file mapping FIDL types to existing Go types")
 
 	var writerType pkg.WriterType
 	generateReceiver := flag.Bool("receiver", false, "generate receiver impl")
 	generateSender := flag.Bool("sender", false, "generate sender impl")
+	generateServer := flag.Bool("server", false, "generate server impl")
+
+	lenient := flag.Bool("lenient", false, "skip malformed FIDL input with a warning instead of failing")
 
 	debug := flag.Bool("debug", false, "debug mode")
 
@@ -30,34 +57,51 @@ func main() {
 		return
 	}
 
-	if (generateReceiver != nil && generateSender != nil) && (!*generateReceiver && !*generateSender) {
-		log.Println("you should decide if you want a receiver or sender impl")
+	generators := 0
+	for _, generate := range []*bool{generateReceiver, generateSender, generateServer} {
+		if *generate {
+			generators++
+		}
+	}
+
+	if generators == 0 {
+		log.Println("you should decide if you want a receiver, sender or server impl")
 		flag.PrintDefaults()
 		return
 	}
 
-	if *generateReceiver && *generateSender {
-		log.Println("you can generate receiver OR sender impl")
+	if generators > 1 {
+		log.Println("you can generate receiver OR sender OR server impl")
 		flag.PrintDefaults()
 		return
 	}
 
-	if *generateSender {
+	switch {
+	case *generateSender:
 		writerType = pkg.SenderWriter
-	} else {
+	case *generateServer:
+		writerType = pkg.ServerWriter
+	default:
 		writerType = pkg.ReceiverWriter
 	}
 
-	file, err := os.Open(*inFile)
-	if err != nil {
-		log.Fatalf("error while reading in file: %v", err)
+	resolver := pkg.NewResolver(includePaths...)
+	if *lenient {
+		resolver.Mode = pkg.Lenient
 	}
-	defer file.Close()
-
-	parser := pkg.NewParser(file)
 
-	fidl, err := parser.Parse()
+	fidl, err := resolver.Load(*inFile)
+	for _, warning := range resolver.Warnings {
+		log.Println("warning:", warning.Snippet())
+	}
 	if err != nil {
+		var errs pkg.ErrorList
+		if errors.As(err, &errs) {
+			for _, parseErr := range errs {
+				log.Println(parseErr.Snippet())
+			}
+			log.Fatalf("%d errors found", len(errs))
+		}
 		log.Fatalln(err)
 	}
 
@@ -71,9 +115,27 @@ func main() {
 		fidl.TargetPackage = fidl.PackageInfo.Name
 	}
 
+	fidl.EnumBackingType = *enumType
+
+	fidl.GoPackages = map[string]string{}
+	for _, mapping := range goPackages {
+		fidlPackage, goPackage, found := strings.Cut(mapping, "=")
+		if !found {
+			log.Fatalln(fmt.Errorf("invalid package mapping %q, expected fidl.package=[name=]go/import/path", mapping))
+		}
+		fidl.GoPackages[fidlPackage] = goPackage
+	}
+
+	if *typeMapFile != "" {
+		fidl.TypeMappings, err = readTypeMappings(*typeMapFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
 	out := os.Stdout
 	if outFile != nil && *outFile != "" {
-		out, err = os.OpenFile(*outFile, os.O_CREATE|os.O_RDWR, 0644)
+		out, err = os.OpenFile(*outFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -85,3 +147,18 @@ func main() {
 		log.Fatalln(err)
 	}
 }
+
+func readTypeMappings(path string) (map[string]pkg.TypeMapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mappings, err := pkg.ReadTypeMappings(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return mappings, nil
+}
@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/SourceFellows/go-fidl-dbus-generator/pkg/lexer"
+)
+
+// ParseError is a syntax error found while parsing a FIDL file.
+type ParseError struct {
+	Pos lexer.Position
+	Msg string
+	// Line is the source line the error is located in.
+	Line string
+}
+
+func (e *ParseError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Snippet returns the error followed by the offending source line and a
+// caret pointing at the column of the error.
+func (e *ParseError) Snippet() string {
+	if e.Line == "" || !e.Pos.IsValid() {
+		return e.Error()
+	}
+
+	// keep tabs so the caret lines up with the source line
+	var indent strings.Builder
+	for i, r := range []rune(e.Line) {
+		if i >= e.Pos.Column-1 {
+			break
+		}
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+
+	return fmt.Sprintf("%s\n%s\n%s^", e.Error(), e.Line, indent.String())
+}
+
+// ErrorList is the list of errors found while parsing a FIDL file.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	messages := make([]string, 0, len(l))
+	for _, err := range l {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Snippet returns the snippets of all errors.
+func (l ErrorList) Snippet() string {
+	snippets := make([]string, 0, len(l))
+	for _, err := range l {
+		snippets = append(snippets, err.Snippet())
+	}
+
+	return strings.Join(snippets, "\n")
+}
+
+// sourceLine returns the 1-based line n of src without its line ending.
+func sourceLine(src []byte, n int) string {
+	if n < 1 {
+		return ""
+	}
+	lines := bytes.Split(src, []byte("\n"))
+	if n > len(lines) {
+		return ""
+	}
+	return strings.TrimRight(string(lines[n-1]), "\r")
+}
@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// removeUnusedImports drops all imports which aren't referenced by the
+// generated source. This way templates can import everything they might
+// need without tracking which features are actually used by a FIDL file.
+// Every import spec is expected on a line of its own.
+func removeUnusedImports(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	unusedLines := map[int]bool{}
+	for _, imp := range file.Imports {
+		name := importName(imp)
+		if name == "_" || name == "." || used[name] {
+			continue
+		}
+		unusedLines[fset.Position(imp.Pos()).Line] = true
+	}
+
+	if len(unusedLines) == 0 {
+		return src, nil
+	}
+
+	var result bytes.Buffer
+	for i, line := range bytes.SplitAfter(src, []byte("\n")) {
+		if !unusedLines[i+1] {
+			result.Write(line)
+		}
+	}
+
+	return result.Bytes(), nil
+}
+
+// importName returns the name under which an import is referenced.
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+
+	path, err := strconv.Unquote(imp.Path.Value)
+	if err != nil {
+		return ""
+	}
+
+	return packageNameOf(path)
+}
+
+// packageNameOf returns the package name of a Go import path. A trailing
+// major version element like v5 is skipped.
+func packageNameOf(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if majorVersionSuffix.MatchString(name) && len(parts) > 1 {
+		name = parts[len(parts)-2]
+	}
+
+	return name
+}
+
+// splitGoPackage splits a Go package mapped to a FIDL package, which is an
+// import path optionally preceded by the package name and =, into the
+// explicit package name and the import path.
+func splitGoPackage(goPackage string) (name, path string) {
+	if name, path, found := strings.Cut(goPackage, "="); found {
+		return name, path
+	}
+
+	return "", goPackage
+}
+
+// goPackageName returns the name a Go package mapped to a FIDL package is
+// referenced by.
+func goPackageName(goPackage string) string {
+	name, path := splitGoPackage(goPackage)
+	if name != "" {
+		return name
+	}
+
+	return packageNameOf(path)
+}
+
+// checkGoPackages returns an error if the package name of a Go package mapped
+// to a FIDL package isn't a valid identifier, e.g. because it is derived from
+// a path like example.com/go-common.
+func checkGoPackages(fidl *Fidl) error {
+	for fidlPackage, goPackage := range fidl.GoPackages {
+		name, path := splitGoPackage(goPackage)
+		if name != "" {
+			if !token.IsIdentifier(name) || name == "_" {
+				return fmt.Errorf("invalid package name %q for FIDL package %s", name, fidlPackage)
+			}
+			continue
+		}
+
+		if !token.IsIdentifier(packageNameOf(path)) {
+			return fmt.Errorf("package name of %s can't be derived from the import path, declare it like %s=name=%s", path, fidlPackage, path)
+		}
+	}
+
+	return nil
+}
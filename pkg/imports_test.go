@@ -0,0 +1,48 @@
+package pkg
+
+import "testing"
+
+func TestRemoveUnusedImports(t *testing.T) {
+
+	//given
+	src := `package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	yaml "gopkg.in/yaml.v3"
+	unit "example.com/go-systemd"
+)
+
+func call(ctx context.Context, service unit.Unit) dbus.ObjectPath {
+	return ""
+}
+`
+
+	//when
+	result, err := removeUnusedImports([]byte(src))
+
+	//then
+	if err != nil {
+		t.Errorf("could not remove imports because of: %v", err)
+		return
+	}
+
+	expected := `package test
+
+import (
+	"context"
+	"github.com/godbus/dbus/v5"
+	unit "example.com/go-systemd"
+)
+
+func call(ctx context.Context, service unit.Unit) dbus.ObjectPath {
+	return ""
+}
+`
+	if string(result) != expected {
+		t.Errorf("got wrong source. expected\n%s\nbut got\n%s", expected, result)
+	}
+
+}
@@ -0,0 +1,24 @@
+package lexer
+
+import "fmt"
+
+// Position is a location in a FIDL file. Line and Column start at 1.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+// IsValid reports whether the position is known.
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+func (pos Position) String() string {
+	location := fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	if pos.Filename != "" {
+		location = fmt.Sprintf("%s:%s", pos.Filename, location)
+	}
+
+	return location
+}
@@ -3,7 +3,9 @@ package lexer
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
+	"strings"
 )
 
 var eof = rune(0)
@@ -11,11 +13,20 @@ var eof = rune(0)
 // Scanner represents a lexical scanner.
 type Scanner struct {
 	r *bufio.Reader
+	// pos is the position of the next rune, prev the one before the last read.
+	pos  Position
+	prev Position
 }
 
 // NewScanner returns a new instance of Scanner.
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{r: bufio.NewReader(r)}
+	return NewFileScanner("", r)
+}
+
+// NewFileScanner returns a new instance of Scanner whose positions refer to
+// filename.
+func NewFileScanner(filename string, r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReader(r), pos: Position{Filename: filename, Line: 1, Column: 1}}
 }
 
 // read reads the next rune from the bufferred reader.
@@ -26,14 +37,42 @@ func (s *Scanner) read() rune {
 		return eof
 	}
 
+	s.prev = s.pos
+	if ch == '\n' {
+		s.pos.Line++
+		s.pos.Column = 1
+	} else {
+		s.pos.Column++
+	}
+
 	return ch
 }
 
 // unread places the previously read rune back on the reader.
-func (s *Scanner) unread() { _ = s.r.UnreadRune() }
+func (s *Scanner) unread() {
+	if s.r.UnreadRune() == nil {
+		s.pos = s.prev
+	}
+}
+
+// peek returns the next rune without consuming it.
+func (s *Scanner) peek() rune {
+	ch := s.read()
+	if ch != eof {
+		s.unread()
+	}
 
-// Scan returns the next token and literal value.
-func (s *Scanner) Scan() (tok Token, lit string) {
+	return ch
+}
+
+// Scan returns the next token, its literal value and its position.
+func (s *Scanner) Scan() (tok Token, lit string, pos Position) {
+	pos = s.pos
+	tok, lit = s.scan()
+	return tok, lit, pos
+}
+
+func (s *Scanner) scan() (tok Token, lit string) {
 	// Read the next rune.
 	ch := s.read()
 
@@ -45,6 +84,16 @@ func (s *Scanner) Scan() (tok Token, lit string) {
 	} else if isLetter(ch) {
 		s.unread()
 		return s.scanIdent()
+	} else if isDigit(ch) {
+		s.unread()
+		return s.scanNumber()
+	} else if ch == '/' && (s.peek() == '/' || s.peek() == '*') {
+		return s.scanComment()
+	} else if ch == '"' {
+		return s.scanString()
+	} else if ch == '-' && isDigit(s.peek()) {
+		tok, lit = s.scanNumber()
+		return tok, fmt.Sprintf("-%s", lit)
 	} else if ch == '<' {
 		ch2 := s.read()
 		if ch2 == '*' {
@@ -62,6 +111,8 @@ func (s *Scanner) Scan() (tok Token, lit string) {
 		return ASTERISK, string(ch)
 	case ',':
 		return COMMA, string(ch)
+	case '=':
+		return EQUALS, string(ch)
 	case '"':
 		return QUOTE, string(ch)
 	case '\'':
@@ -158,18 +209,140 @@ func (s *Scanner) scanIdent() (tok Token, lit string) {
 		return SELECTIVE, buf.String()
 	case "fireAndForget":
 		return FIRE_AND_FORGET, buf.String()
+	case "enumeration":
+		return ENUMERATION, buf.String()
+	case "extends":
+		return EXTENDS, buf.String()
+	case "map":
+		return MAP, buf.String()
+	case "to":
+		return TO, buf.String()
+	case "union":
+		return UNION, buf.String()
+	case "const":
+		return CONST, buf.String()
+	case "typeCollection":
+		return TYPE_COLLECTION, buf.String()
+	case "error":
+		return ERROR, buf.String()
+	case "readonly":
+		return READONLY, buf.String()
+	case "noSubscriptions":
+		return NO_SUBSCRIPTIONS, buf.String()
+	case "true", "false":
+		return BOOLEAN, buf.String()
 	}
 
 	// Otherwise return as a regular identifier.
 	return IDENT, buf.String()
 }
 
+// scanNumber consumes the current rune and all contiguous number runes.
+// Integers may be given decimal, hexadecimal (0x) or binary (0b). Floats
+// contain a decimal point or exponent and may carry a 'f' or 'd' suffix.
+func (s *Scanner) scanNumber() (tok Token, lit string) {
+	// Create a buffer and read the current character into it.
+	var buf bytes.Buffer
+	buf.WriteRune(s.read())
+
+	// Read every subsequent digit, letter or decimal point into the buffer.
+	// Any other character and EOF will cause the loop to exit.
+	for {
+		ch := s.read()
+		if ch == eof {
+			break
+		} else if (ch == '-' || ch == '+') && isExponent(buf.String()) {
+			_, _ = buf.WriteRune(ch)
+		} else if !isDigit(ch) && !isLetter(ch) && ch != '.' {
+			s.unread()
+			break
+		} else {
+			_, _ = buf.WriteRune(ch)
+		}
+	}
+
+	lit = buf.String()
+	if isHex(lit) {
+		return INTEGER, lit
+	}
+
+	if strings.ContainsAny(lit, ".eE") || strings.ContainsAny(lit[len(lit)-1:], "fFdD") {
+		return FLOAT, lit
+	}
+
+	return INTEGER, lit
+}
+
+// scanComment consumes a line comment (//) or a block comment (/* */).
+// The leading '/' has already been read. Comments aren't part of the FIDL
+// model, the parser skips them like whitespace.
+func (s *Scanner) scanComment() (tok Token, lit string) {
+	var buf bytes.Buffer
+	buf.WriteRune('/')
+
+	block := s.read() == '*'
+	if block {
+		buf.WriteRune('*')
+	} else {
+		buf.WriteRune('/')
+	}
+
+	for {
+		ch := s.read()
+		if ch == eof && block {
+			// unterminated block comment
+			return ILLEGAL, "/*"
+		} else if ch == eof {
+			break
+		}
+
+		if !block && ch == '\n' {
+			s.unread()
+			break
+		}
+
+		buf.WriteRune(ch)
+		if block && ch == '*' && s.peek() == '/' {
+			buf.WriteRune(s.read())
+			break
+		}
+	}
+
+	return COMMENT, buf.String()
+}
+
+// scanString consumes a string literal. The opening quote has already been
+// read. The returned literal is the content without quotes, escape
+// sequences are kept as they are.
+func (s *Scanner) scanString() (tok Token, lit string) {
+	var buf bytes.Buffer
+
+	for {
+		ch := s.read()
+		if ch == eof || ch == '"' {
+			break
+		}
+
+		buf.WriteRune(ch)
+		if ch == '\\' {
+			buf.WriteRune(s.read())
+		}
+	}
+
+	return STRING, buf.String()
+}
+
 func (s *Scanner) scanDescription() (tok Token, lit string) {
 	// Create a buffer and read the current character into it.
 	var buf bytes.Buffer
 
 	for {
 		ch := s.read()
+		if ch == eof {
+			// unterminated description
+			return ILLEGAL, "<**"
+		}
+
 		// TODO: use better approach to determine end of description
 		if ch != '*' {
 			buf.WriteRune(ch)
@@ -201,3 +374,13 @@ func isLetter(ch rune) bool {
 func isDigit(ch rune) bool {
 	return ch >= '0' && ch <= '9'
 }
+
+func isHex(number string) bool {
+	return strings.HasPrefix(number, "0x") || strings.HasPrefix(number, "0X")
+}
+
+// isExponent reports whether number ends with the exponent marker of a
+// float so that a following sign belongs to the number.
+func isExponent(number string) bool {
+	return !isHex(number) && (strings.HasSuffix(number, "e") || strings.HasSuffix(number, "E"))
+}
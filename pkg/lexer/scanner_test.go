@@ -0,0 +1,139 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScan_Literals(t *testing.T) {
+
+	//given
+	table := []struct {
+		input       string
+		expectedTok Token
+		expectedLit string
+	}{
+		{"42", INTEGER, "42"},
+		{"-42", INTEGER, "-42"},
+		{"0x1F", INTEGER, "0x1F"},
+		{"0b101", INTEGER, "0b101"},
+		{"1.5", FLOAT, "1.5"},
+		{"1.5f", FLOAT, "1.5f"},
+		{"2d", FLOAT, "2d"},
+		{"1e-9", FLOAT, "1e-9"},
+		{`"text"`, STRING, "text"},
+		{`"say \"hi\""`, STRING, `say \"hi\"`},
+		{"true", BOOLEAN, "true"},
+		{"const", CONST, "const"},
+	}
+	for _, row := range table {
+
+		tok, lit, _ := NewScanner(strings.NewReader(row.input)).Scan()
+
+		if tok != row.expectedTok || lit != row.expectedLit {
+			t.Errorf("got wrong token for %s. expected %v %q but got %v %q", row.input, row.expectedTok, row.expectedLit, tok, lit)
+		}
+
+	}
+
+}
+
+func TestScan_Comments(t *testing.T) {
+
+	//given
+	scanner := NewScanner(strings.NewReader("// line } comment\n/* block\n { comment */ }"))
+
+	//when
+	var tokens []Token
+	var literals []string
+	for {
+		tok, lit, _ := scanner.Scan()
+		if tok == EOF {
+			break
+		}
+		tokens = append(tokens, tok)
+		literals = append(literals, lit)
+	}
+
+	//then
+	expected := []Token{COMMENT, WHITESPACE, COMMENT, WHITESPACE, CURLY_BRACKET_CLOSE}
+	if len(tokens) != len(expected) {
+		t.Errorf("got wrong tokens. expected %v but got %v", expected, tokens)
+		return
+	}
+
+	for i, tok := range tokens {
+		if tok != expected[i] {
+			t.Errorf("got wrong tokens. expected %v but got %v", expected, tokens)
+			return
+		}
+	}
+
+	if literals[0] != "// line } comment" || literals[2] != "/* block\n { comment */" {
+		t.Errorf("got wrong comments %q and %q", literals[0], literals[2])
+	}
+
+}
+
+func TestScan_UnterminatedComment(t *testing.T) {
+
+	//given
+	scanner := NewScanner(strings.NewReader("/* never closed"))
+
+	//when
+	tok, lit, _ := scanner.Scan()
+
+	//then
+	if tok != ILLEGAL || lit != "/*" {
+		t.Errorf("expected ILLEGAL /* but got %v %q", tok, lit)
+	}
+
+	if tok, _, _ = scanner.Scan(); tok != EOF {
+		t.Errorf("expected EOF but got %v", tok)
+	}
+
+}
+
+func TestScan_Positions(t *testing.T) {
+
+	//given
+	scanner := NewFileScanner("Test.fidl", strings.NewReader("package org.example\n\n  <** doc\n **> interface\tTest {"))
+
+	//when
+	var positions []string
+	for {
+		tok, _, pos := scanner.Scan()
+		if tok == EOF {
+			break
+		}
+		if tok != WHITESPACE {
+			positions = append(positions, pos.String())
+		}
+	}
+
+	//then
+	expected := []string{"Test.fidl:1:1", "Test.fidl:1:9", "Test.fidl:3:3", "Test.fidl:4:6", "Test.fidl:4:16", "Test.fidl:4:21"}
+	if strings.Join(positions, " ") != strings.Join(expected, " ") {
+		t.Errorf("got wrong positions. expected %v but got %v", expected, positions)
+	}
+
+}
+
+func TestScan_UnterminatedDescription(t *testing.T) {
+
+	//given
+	scanner := NewScanner(strings.NewReader("<** never closed"))
+
+	//when
+	tok, lit, _ := scanner.Scan()
+
+	//then
+	if tok != ILLEGAL || lit != "<**" {
+		t.Errorf("expected ILLEGAL <** but got %v %q", tok, lit)
+	}
+
+	if tok, _, _ = scanner.Scan(); tok != EOF {
+		t.Errorf("expected EOF but got %v", tok)
+	}
+
+}
@@ -10,10 +10,16 @@ const (
 	// Literals
 	IDENT
 	DESCRIPTION
+	COMMENT
+	INTEGER
+	FLOAT
+	STRING
+	BOOLEAN
 
 	// Misc characters
 	ASTERISK             // *
 	COMMA                // ,
+	EQUALS               // =
 	QUOTE                // "
 	SINGLE_QUOTE         // '
 	CIRCUMFLEX           // ^
@@ -39,4 +45,14 @@ const (
 	IN
 	OUT
 	FIRE_AND_FORGET
+	ENUMERATION
+	EXTENDS
+	MAP
+	TO
+	UNION
+	CONST
+	TYPE_COLLECTION
+	ERROR
+	READONLY
+	NO_SUBSCRIPTIONS
 )
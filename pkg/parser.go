@@ -1,34 +1,77 @@
 package pkg
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/SourceFellows/go-fidl-dbus-generator/pkg/lexer"
 	"io"
 	"strconv"
 )
 
-// Fidl represents a FIDL file.
+// Fidl represents a FIDL file. Pos of the nodes is the position they are
+// declared at.
 type (
 	Fidl struct {
-		TargetPackage string
-		PackageInfo   *PackageInfo
-		InterfaceInfo *InterfaceInfo
-		Attributes    []Attribute
-		Methods       []Method
-		Broadcasts    []Broadcast
-		Structs       []Struct
-		TypeDefs      []TypeDef
-		ArrayDef      []ArrayDef
+		TargetPackage   string
+		PackageInfo     *PackageInfo
+		TypeCollections []TypeCollection
+		Interfaces      []Interface
+
+		// EnumBackingType is the FIDL integer type enumerations are
+		// marshalled as. Int32 is used if empty.
+		EnumBackingType string
+
+		// GoPackages maps FIDL package names to Go import paths, optionally
+		// preceded by the package name and = (yaml=gopkg.in/yaml.v3). Types
+		// of imported FIDL packages without mapping are generated in place.
+		GoPackages map[string]string
+
+		// TypeMappings maps FIDL type names to existing Go types, which are
+		// used instead of generated ones.
+		TypeMappings map[string]TypeMapping
+	}
+
+	// Types holds the type definitions of an interface or type collection.
+	Types struct {
+		Structs      []Struct
+		TypeDefs     []TypeDef
+		ArrayDef     []ArrayDef
+		Enumerations []Enumeration
+		Maps         []MapDef
+		Unions       []Union
+		Constants    []Constant
+	}
+
+	TypeCollection struct {
+		Name         string
+		Description  string
+		MajorVersion int
+		MinorVersion int
+		Types
+		Pos lexer.Position
+	}
+
+	Interface struct {
+		InterfaceInfo
+		Attributes []Attribute
+		Methods    []Method
+		Broadcasts []Broadcast
+		Types
 	}
 
 	PackageInfo struct {
 		Name    string
 		Imports []Import
+		Pos     lexer.Position
 	}
 
 	Import struct {
 		Path string
 		From string
+		// Fidl is the imported file. It is set by the Resolver.
+		Fidl *Fidl
+		Pos  lexer.Position
 	}
 
 	InterfaceInfo struct {
@@ -36,6 +79,9 @@ type (
 		Description  string
 		MajorVersion int
 		MinorVersion int
+		// Extends is the name of the base interface, if any.
+		Extends string
+		Pos     lexer.Position
 	}
 
 	Attribute struct {
@@ -43,6 +89,11 @@ type (
 		Type        string
 		Name        string
 		IsArray     bool
+		// ReadOnly attributes can't be changed by clients.
+		ReadOnly bool
+		// NoSubscriptions attributes don't notify about changes.
+		NoSubscriptions bool
+		Pos             lexer.Position
 	}
 
 	Method struct {
@@ -51,6 +102,18 @@ type (
 		FireAndForget bool
 		In            []Param
 		Out           []Param
+		// Error lists the errors the method may return, nil if none are declared.
+		Error *MethodError
+		Pos   lexer.Position
+	}
+
+	// MethodError declares the errors of a method. They either reference an
+	// enumeration by Type or are declared inline.
+	MethodError struct {
+		Type        string
+		Extends     string
+		Enumerators []Enumerator
+		Pos         lexer.Position
 	}
 
 	Broadcast struct {
@@ -58,6 +121,7 @@ type (
 		Name        string
 		IsSelective bool
 		Out         []Param
+		Pos         lexer.Position
 	}
 
 	Param struct {
@@ -65,139 +129,197 @@ type (
 		Type        string
 		Name        string
 		IsArray     bool
+		Pos         lexer.Position
 	}
 
 	Struct struct {
 		Description string
 		Name        string
 		Fields      []Param
+		Pos         lexer.Position
 	}
 
 	TypeDef struct {
 		Description string
 		Name        string
 		Type        string
+		Pos         lexer.Position
 	}
 
 	ArrayDef struct {
 		Description string
 		Name        string
 		Type        string
+		Pos         lexer.Position
+	}
+
+	Constant struct {
+		Description string
+		Type        string
+		Name        string
+		// Value is the literal as written in the FIDL file. Strings are
+		// kept in double quotes.
+		Value string
+		Pos   lexer.Position
+	}
+
+	Union struct {
+		Description string
+		Name        string
+		Fields      []Param
+		Pos         lexer.Position
+	}
+
+	MapDef struct {
+		Description string
+		Name        string
+		KeyType     string
+		ValueType   string
+		Pos         lexer.Position
+	}
+
+	Enumeration struct {
+		Description string
+		Name        string
+		Extends     string
+		Enumerators []Enumerator
+		Pos         lexer.Position
+	}
+
+	Enumerator struct {
+		Description string
+		Name        string
+		Value       string
+		Pos         lexer.Position
 	}
 )
 
+// Mode controls how the parser handles malformed input.
+type Mode int
+
+const (
+	// Strict reports malformed input as error.
+	Strict Mode = iota
+	// Lenient skips malformed input and records it in the warnings.
+	Lenient
+)
+
 // Parser represents a parser.
 type Parser struct {
+	// Mode is Strict unless it is changed before parsing.
+	Mode Mode
+	// Warnings holds the malformed input skipped in lenient mode.
+	Warnings []*ParseError
+
+	errors ErrorList
+	// recoveries holds the positions lenient recoveries started at, see warn.
+	recoveries []lexer.Position
+	// depth is the number of currently open curly brackets.
+	depth int
+
+	r        io.Reader
+	filename string
+	// src is the parsed source, it is kept to show it in errors.
+	src []byte
 	s   *lexer.Scanner
 	buf struct {
-		tok lexer.Token // last read token
-		lit string      // last read literal
-		n   int         // buffer size (max=1)
+		tok lexer.Token    // last read token
+		lit string         // last read literal
+		pos lexer.Position // position of last read token
+		n   int            // buffer size (max=1)
 	}
 }
 
 // NewParser returns a new instance of Parser.
 func NewParser(r io.Reader) *Parser {
-	return &Parser{s: lexer.NewScanner(r)}
+	return NewFileParser("", r)
 }
 
-// Parse parses a FIDL file.
-func (p *Parser) Parse() (*Fidl, error) {
-	fidl := &Fidl{
-		PackageInfo:   nil,
-		InterfaceInfo: nil,
-		Attributes:    nil,
-		Methods:       nil,
-		Broadcasts:    nil,
-		Structs:       nil,
-	}
+// NewFileParser returns a new instance of Parser whose positions and errors
+// refer to filename.
+func NewFileParser(filename string, r io.Reader) *Parser {
+	return &Parser{r: r, filename: filename}
+}
 
-	packageInfo, err := p.scanPackageInfo()
+// Parse parses a FIDL file. Syntax errors are returned as ErrorList. The
+// parser recovers from errors at the next declaration, so the list holds all
+// errors found in the file.
+func (p *Parser) Parse() (*Fidl, error) {
+	src, err := io.ReadAll(p.r)
 	if err != nil {
 		return nil, err
 	}
+	p.src = src
+	p.s = lexer.NewFileScanner(p.filename, bytes.NewReader(src))
 
-	fidl.PackageInfo = packageInfo
+	fidl := &Fidl{
+		PackageInfo:     nil,
+		TypeCollections: nil,
+		Interfaces:      nil,
+	}
 
-	interfaceInfo, err := p.scanInterfaceInfo()
+	packageInfo, err := p.scanPackageInfo()
 	if err != nil {
-		return nil, err
+		p.recoverFrom(err, 0, fileDeclarations)
 	}
 
-	fidl.InterfaceInfo = interfaceInfo
+	fidl.PackageInfo = packageInfo
 
 	for {
-		innerTok, innerLit := p.scanIgnoreWhitespace()
-		if innerTok == lexer.EOF {
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.EOF {
 			break
 		}
 
 		var description string
-		if innerTok == lexer.DESCRIPTION {
-			description = innerLit
-			innerTok, innerLit = p.scanIgnoreWhitespace()
+		if tok == lexer.DESCRIPTION {
+			description = lit
+			tok, lit = p.scanIgnoreWhitespace()
 		}
 
-		switch innerTok {
-		case lexer.ATTRIBUTE:
-			if fidl.Attributes == nil {
-				fidl.Attributes = []Attribute{}
+		switch tok {
+		case lexer.INTERFACE:
+			if fidl.Interfaces == nil {
+				fidl.Interfaces = []Interface{}
 			}
 
-			attr := p.scanAttribute()
-			attr.Description = description
-
-			fidl.Attributes = append(fidl.Attributes, attr)
-		case lexer.METHOD:
-			if fidl.Methods == nil {
-				fidl.Methods = []Method{}
+			iface, err := p.scanInterface()
+			if err != nil {
+				p.recoverFrom(err, 0, fileDeclarations)
+				continue
 			}
+			iface.Description = description
 
-			meth := p.scanMethod()
-			meth.Description = description
-
-			fidl.Methods = append(fidl.Methods, meth)
-		case lexer.BROADCAST:
-			if fidl.Broadcasts == nil {
-				fidl.Broadcasts = []Broadcast{}
+			fidl.Interfaces = append(fidl.Interfaces, iface)
+		case lexer.TYPE_COLLECTION:
+			if fidl.TypeCollections == nil {
+				fidl.TypeCollections = []TypeCollection{}
 			}
 
-			bc := p.scanBroadcast()
-			bc.Description = description
-
-			fidl.Broadcasts = append(fidl.Broadcasts, bc)
-		case lexer.STRUCT:
-			if fidl.Structs == nil {
-				fidl.Structs = []Struct{}
-			}
-
-			str := p.scanStruct()
-			str.Description = description
-
-			fidl.Structs = append(fidl.Structs, str)
-		case lexer.TYPEDEF:
-			if fidl.TypeDefs == nil {
-				fidl.TypeDefs = []TypeDef{}
+			typeCollection, err := p.scanTypeCollection()
+			if err != nil {
+				p.recoverFrom(err, 0, fileDeclarations)
+				continue
 			}
+			typeCollection.Description = description
 
-			td := p.scanTypeDefs()
-			td.Description = description
-
-			fidl.TypeDefs = append(fidl.TypeDefs, td)
-		case lexer.ARRAYDEF:
-			if fidl.ArrayDef == nil {
-				fidl.ArrayDef = []ArrayDef{}
+			fidl.TypeCollections = append(fidl.TypeCollections, typeCollection)
+		default:
+			if err := p.malformed("expected interface or typeCollection but got %s", describe(tok, lit)); err != nil {
+				p.recoverFrom(err, 0, fileDeclarations)
+			} else {
+				// skip the unknown declaration with all its blocks
+				p.skipTo(0, fileDeclarations)
 			}
+		}
+	}
 
-			arr := p.scanArrayDefs()
-			arr.Description = description
-
-			fidl.ArrayDef = append(fidl.ArrayDef, arr)
-		default:
-			// ignore unknown input for now
-			continue
+	if len(p.errors) > 0 {
+		return nil, p.errors
+	}
 
-		}
+	if fidl.Interfaces == nil && fidl.TypeCollections == nil {
+		return nil, ErrorList{p.errorf(p.pos(), "expected interface or typeCollection definition")}
 	}
 
 	// Return the successfully parsed FIDL.
@@ -205,17 +327,17 @@ func (p *Parser) Parse() (*Fidl, error) {
 }
 
 func (p *Parser) scanPackageInfo() (*PackageInfo, error) {
-	packageInfo := &PackageInfo{}
-
 	// First token should be a "package" keyword.
 	tok, lit := p.scanIgnoreWhitespace()
 	if tok != lexer.PACKAGE {
-		return nil, fmt.Errorf("found %q, expected package", lit)
+		return nil, p.errorf(p.pos(), "found %q, expected package", lit)
 	}
 
+	packageInfo := &PackageInfo{Pos: p.pos()}
+
 	tok, lit = p.scanIgnoreWhitespace()
 	if tok != lexer.IDENT {
-		return nil, fmt.Errorf("expected IDENT, got %v", tok)
+		return nil, p.errorf(p.pos(), "expected package name but got %q", lit)
 	}
 
 	packageInfo.Name = lit
@@ -224,30 +346,42 @@ func (p *Parser) scanPackageInfo() (*PackageInfo, error) {
 	for {
 		tok, lit = p.scanIgnoreWhitespace()
 		if tok == lexer.IMPORT {
-			imp := Import{}
+			imp := Import{Pos: p.pos()}
 			tok, lit = p.scanIgnoreWhitespace()
+			if err := p.check(tok, lit, lexer.IDENT, "imported package or model name"); err != nil {
+				if errors.Is(err, errDropped) {
+					// only the import is skipped, the token is parsed again
+					p.unscan()
+					continue
+				}
+				return nil, err
+			}
 			imp.Path = lit
 			tok, lit = p.scanIgnoreWhitespace()
 			if tok == lexer.ASTERISK {
 				imp.Path = fmt.Sprintf("%s%s", imp.Path, lit)
-				// ignore "from" keyword
-				p.scanIgnoreWhitespace()
+				tok, lit = p.scanIgnoreWhitespace()
 			}
 
-			tok, lit = p.scanIgnoreWhitespace()
-			if tok == lexer.QUOTE {
-				// ignore quote and continue scan
+			if tok != lexer.STRING {
+				if tok != lexer.IDENT || lit != "from" {
+					if err := p.malformed("expected from but got %s", describe(tok, lit)); err != nil {
+						return nil, err
+					}
+				}
 				tok, lit = p.scanIgnoreWhitespace()
 			}
 
+			if err := p.check(tok, lit, lexer.STRING, "imported file name"); err != nil {
+				if errors.Is(err, errDropped) {
+					p.unscan()
+					continue
+				}
+				return nil, err
+			}
 			imp.From = lit
 			imports = append(imports, imp)
 
-			tok, lit = p.scanIgnoreWhitespace()
-			if tok != lexer.QUOTE {
-				p.unscan()
-			}
-
 			continue
 		}
 
@@ -260,267 +394,860 @@ func (p *Parser) scanPackageInfo() (*PackageInfo, error) {
 	return packageInfo, nil
 }
 
-func (p *Parser) scanInterfaceInfo() (*InterfaceInfo, error) {
-	interfaceInfo := &InterfaceInfo{}
+func (p *Parser) scanInterface() (Interface, error) {
+	iface := Interface{}
 
-	tok, lit := p.scanIgnoreWhitespace()
-	var desc string
-	if tok == lexer.DESCRIPTION {
-		desc = lit
-		// ignore keyword "interface" after description
-		p.scanIgnoreWhitespace()
-	}
-
-	if tok != lexer.DESCRIPTION && tok != lexer.INTERFACE {
-		return nil, fmt.Errorf("expected interface definition but got %v: %v", tok, lit)
+	interfaceInfo, err := p.scanInterfaceInfo()
+	if err != nil {
+		return iface, err
 	}
 
-	tok, lit = p.scanIgnoreWhitespace()
+	iface.InterfaceInfo = *interfaceInfo
 
-	interfaceInfo.Description = desc
-	interfaceInfo.Name = lit
+	// members recover from errors at the depth of the interface body
+	depth := p.depth
+	for {
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.CURLY_BRACKET_CLOSE {
+			break
+		}
 
-	// ignore "{" of interface start
-	p.scanIgnoreWhitespace()
+		if tok == lexer.EOF {
+			if err := p.malformed("expected } to close interface %s but got %s", iface.Name, describe(tok, lit)); err != nil {
+				p.recoverFrom(err, depth, interfaceDeclarations)
+			}
+			break
+		}
 
-	// scan version
-	tok, lit = p.scanIgnoreWhitespace()
-	if tok == lexer.VERSION {
-		for {
+		var description string
+		if tok == lexer.DESCRIPTION {
+			description = lit
 			tok, lit = p.scanIgnoreWhitespace()
-			if tok == lexer.CURLY_BRACKET_OPEN {
+		}
+
+		switch tok {
+		case lexer.ATTRIBUTE:
+			if iface.Attributes == nil {
+				iface.Attributes = []Attribute{}
+			}
+
+			attr, err := p.scanAttribute()
+			if err != nil {
+				p.recoverFrom(err, depth, interfaceDeclarations)
 				continue
 			}
+			attr.Description = description
 
-			if tok == lexer.MAJOR {
-				tok, lit = p.scanIgnoreWhitespace()
-				majorVersion, err := strconv.Atoi(lit)
+			iface.Attributes = append(iface.Attributes, attr)
+		case lexer.METHOD:
+			if iface.Methods == nil {
+				iface.Methods = []Method{}
+			}
+
+			meth, err := p.scanMethod()
+			if err != nil {
+				p.recoverFrom(err, depth, interfaceDeclarations)
+				continue
+			}
+			meth.Description = description
+
+			iface.Methods = append(iface.Methods, meth)
+		case lexer.BROADCAST:
+			if iface.Broadcasts == nil {
+				iface.Broadcasts = []Broadcast{}
+			}
+
+			bc, err := p.scanBroadcast()
+			if err != nil {
+				p.recoverFrom(err, depth, interfaceDeclarations)
+				continue
+			}
+			bc.Description = description
+
+			iface.Broadcasts = append(iface.Broadcasts, bc)
+		default:
+			ok, err := p.scanTypeDefinition(tok, description, &iface.Types)
+			if err != nil {
+				p.recoverFrom(err, depth, interfaceDeclarations)
+				continue
+			}
+
+			if !ok {
+				err := p.malformed("expected attribute, method, broadcast or type definition in interface %s but got %s", iface.Name, describe(tok, lit))
 				if err != nil {
-					return nil, err
+					p.recoverFrom(err, depth, interfaceDeclarations)
+				} else {
+					// skip the unknown declaration with all its blocks
+					p.skipTo(depth, interfaceDeclarations)
 				}
+			}
+		}
+	}
+
+	return iface, nil
+}
+
+func (p *Parser) scanInterfaceInfo() (*InterfaceInfo, error) {
+	interfaceInfo := &InterfaceInfo{Pos: p.pos()}
+
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok != lexer.IDENT {
+		return nil, p.errorf(p.pos(), "expected interface name but got %q", lit)
+	}
+
+	interfaceInfo.Name = lit
+
+	tok, lit = p.scanIgnoreWhitespace()
+	if tok == lexer.EXTENDS {
+		tok, lit = p.scanIgnoreWhitespace()
+		if tok != lexer.IDENT {
+			return nil, p.errorf(p.pos(), "expected base interface name of %s but got %q", interfaceInfo.Name, lit)
+		}
+
+		interfaceInfo.Extends = lit
+		tok, lit = p.scanIgnoreWhitespace()
+	}
+
+	if tok != lexer.CURLY_BRACKET_OPEN {
+		return nil, p.errorf(p.pos(), "expected { after interface %s but got %q", interfaceInfo.Name, lit)
+	}
 
-				interfaceInfo.MajorVersion = majorVersion
+	majorVersion, minorVersion, err := p.scanVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	interfaceInfo.MajorVersion = majorVersion
+	interfaceInfo.MinorVersion = minorVersion
+
+	return interfaceInfo, nil
+}
+
+func (p *Parser) scanTypeCollection() (TypeCollection, error) {
+	typeCollection := TypeCollection{Pos: p.pos()}
+
+	// the name of a type collection is optional
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok == lexer.IDENT {
+		typeCollection.Name = lit
+		tok, lit = p.scanIgnoreWhitespace()
+	}
+
+	if err := p.check(tok, lit, lexer.CURLY_BRACKET_OPEN, "{ after typeCollection"); err != nil {
+		return typeCollection, err
+	}
+
+	majorVersion, minorVersion, err := p.scanVersion()
+	if err != nil {
+		return typeCollection, err
+	}
+
+	typeCollection.MajorVersion = majorVersion
+	typeCollection.MinorVersion = minorVersion
+
+	// type definitions recover from errors at the depth of the collection body
+	depth := p.depth
+	for {
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.CURLY_BRACKET_CLOSE {
+			break
+		}
+
+		if tok == lexer.EOF {
+			if err := p.malformed("expected } to close typeCollection %s but got %s", typeCollection.Name, describe(tok, lit)); err != nil {
+				p.recoverFrom(err, depth, typeDeclarations)
 			}
+			break
+		}
 
+		var description string
+		if tok == lexer.DESCRIPTION {
+			description = lit
 			tok, lit = p.scanIgnoreWhitespace()
-			if tok == lexer.MINOR {
-				tok, lit = p.scanIgnoreWhitespace()
-				minorVersion, err := strconv.Atoi(lit)
-				if err != nil {
-					return nil, err
-				}
+		}
 
-				interfaceInfo.MinorVersion = minorVersion
+		ok, err := p.scanTypeDefinition(tok, description, &typeCollection.Types)
+		if err != nil {
+			p.recoverFrom(err, depth, typeDeclarations)
+			continue
+		}
+
+		if !ok {
+			if err := p.malformed("expected type definition in typeCollection %s but got %s", typeCollection.Name, describe(tok, lit)); err != nil {
+				p.recoverFrom(err, depth, typeDeclarations)
+			} else {
+				// skip the unknown declaration with all its blocks
+				p.skipTo(depth, typeDeclarations)
 			}
+		}
+	}
+
+	return typeCollection, nil
+}
+
+// scanVersion scans an optional version block.
+func (p *Parser) scanVersion() (int, int, error) {
+	var majorVersion, minorVersion int
 
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok != lexer.VERSION {
+		p.unscan()
+		return majorVersion, minorVersion, nil
+	}
+
+	tok, lit = p.scanIgnoreWhitespace()
+	if tok != lexer.CURLY_BRACKET_OPEN {
+		if err := p.malformed("expected { after version but got %s", describe(tok, lit)); err != nil {
+			return majorVersion, minorVersion, err
+		}
+		p.unscan()
+		// the block is treated as opened, so its } doesn't close the enclosing one
+		p.depth++
+	}
+
+	for {
+		tok, lit = p.scanIgnoreWhitespace()
+		if tok == lexer.CURLY_BRACKET_CLOSE {
+			break
+		}
+
+		if tok == lexer.MAJOR || tok == lexer.MINOR {
+			versionTok := tok
+			tok, lit = p.scanIgnoreWhitespace()
+			version, err := strconv.Atoi(lit)
+			if err != nil {
+				return majorVersion, minorVersion, p.errorf(p.pos(), "expected version number but got %q", lit)
+			}
+
+			if versionTok == lexer.MAJOR {
+				majorVersion = version
+			} else {
+				minorVersion = version
+			}
+
+			continue
+		}
+
+		if err := p.malformed("expected major, minor or } in version but got %s", describe(tok, lit)); err != nil {
+			return majorVersion, minorVersion, err
+		}
+
+		if tok == lexer.EOF {
 			break
 		}
 	}
 
-	return interfaceInfo, nil
+	return majorVersion, minorVersion, nil
+}
+
+// scanTypeDefinition scans the type definition started by tok into types.
+// It returns false if tok doesn't start a type definition.
+func (p *Parser) scanTypeDefinition(tok lexer.Token, description string, types *Types) (bool, error) {
+	switch tok {
+	case lexer.STRUCT:
+		if types.Structs == nil {
+			types.Structs = []Struct{}
+		}
+
+		str, err := p.scanStruct()
+		if err != nil {
+			return true, err
+		}
+		str.Description = description
+
+		types.Structs = append(types.Structs, str)
+	case lexer.TYPEDEF:
+		if types.TypeDefs == nil {
+			types.TypeDefs = []TypeDef{}
+		}
+
+		td, err := p.scanTypeDefs()
+		if err != nil {
+			return true, err
+		}
+		td.Description = description
+
+		types.TypeDefs = append(types.TypeDefs, td)
+	case lexer.ARRAYDEF:
+		if types.ArrayDef == nil {
+			types.ArrayDef = []ArrayDef{}
+		}
+
+		arr, err := p.scanArrayDefs()
+		if err != nil {
+			return true, err
+		}
+		arr.Description = description
+
+		types.ArrayDef = append(types.ArrayDef, arr)
+	case lexer.ENUMERATION:
+		if types.Enumerations == nil {
+			types.Enumerations = []Enumeration{}
+		}
+
+		enum, err := p.scanEnumeration()
+		if err != nil {
+			return true, err
+		}
+		enum.Description = description
+
+		types.Enumerations = append(types.Enumerations, enum)
+	case lexer.MAP:
+		if types.Maps == nil {
+			types.Maps = []MapDef{}
+		}
+
+		mapDef, err := p.scanMapDef()
+		if err != nil {
+			return true, err
+		}
+		mapDef.Description = description
+
+		types.Maps = append(types.Maps, mapDef)
+	case lexer.UNION:
+		if types.Unions == nil {
+			types.Unions = []Union{}
+		}
+
+		union, err := p.scanUnion()
+		if err != nil {
+			return true, err
+		}
+		union.Description = description
+
+		types.Unions = append(types.Unions, union)
+	case lexer.CONST:
+		if types.Constants == nil {
+			types.Constants = []Constant{}
+		}
+
+		constant, err := p.scanConstant()
+		if err != nil {
+			return true, err
+		}
+		constant.Description = description
+
+		types.Constants = append(types.Constants, constant)
+	default:
+		return false, nil
+	}
+
+	return true, nil
 }
 
-func (p *Parser) scanAttribute() Attribute {
-	attr := Attribute{}
-	_, lit := p.scanIgnoreWhitespace()
+func (p *Parser) scanAttribute() (Attribute, error) {
+	attr := Attribute{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "attribute type")
+	if err != nil {
+		return attr, err
+	}
 	attr.Type = lit
+
 	tok, lit := p.scanIgnoreWhitespace()
 	if tok == lexer.SQUARE_BRACKET_OPEN {
 		attr.IsArray = true
-		// ignore SQUARE_BRACKET_CLOSE
-		p.scanIgnoreWhitespace()
+		if _, err := p.expect(lexer.SQUARE_BRACKET_CLOSE, "]"); err != nil {
+			return attr, err
+		}
 
 		// scan param name
-		_, lit = p.scanIgnoreWhitespace()
-		attr.Name = lit
-	} else {
-		attr.Name = lit
+		tok, lit = p.scanIgnoreWhitespace()
+	}
+
+	if err := p.check(tok, lit, lexer.IDENT, "attribute name"); err != nil {
+		return attr, err
 	}
+	attr.Name = lit
 
-	return attr
+	// scan optional modifiers
+	for {
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok != lexer.READONLY && tok != lexer.NO_SUBSCRIPTIONS {
+			p.unscan()
+			break
+		}
+
+		if (tok == lexer.READONLY && attr.ReadOnly) || (tok == lexer.NO_SUBSCRIPTIONS && attr.NoSubscriptions) {
+			if err := p.malformed("modifier %s declared twice for attribute %s", lit, attr.Name); err != nil {
+				return attr, err
+			}
+		}
+
+		attr.ReadOnly = attr.ReadOnly || tok == lexer.READONLY
+		attr.NoSubscriptions = attr.NoSubscriptions || tok == lexer.NO_SUBSCRIPTIONS
+	}
+
+	return attr, nil
 }
 
-func (p *Parser) scanMethod() Method {
-	meth := Method{}
-	_, lit := p.scanIgnoreWhitespace()
+func (p *Parser) scanMethod() (Method, error) {
+	meth := Method{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "method name")
+	if err != nil {
+		return meth, err
+	}
 	meth.Name = lit
-	meth.In, meth.Out, meth.FireAndForget = p.scanParams()
 
-	return meth
+	meth.In, meth.Out, meth.Error, meth.FireAndForget, err = p.scanParams("method", meth.Name)
+
+	return meth, err
 }
 
-func (p *Parser) scanBroadcast() Broadcast {
-	bc := Broadcast{}
-	_, lit := p.scanIgnoreWhitespace()
+func (p *Parser) scanBroadcast() (Broadcast, error) {
+	bc := Broadcast{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "broadcast name")
+	if err != nil {
+		return bc, err
+	}
 	bc.Name = lit
 
-	tok, lit := p.scanIgnoreWhitespace()
-	if tok == lexer.SELECTIVE {
-		fmt.Println("### selective", lit)
+	if tok, _ := p.scanIgnoreWhitespace(); tok == lexer.SELECTIVE {
 		bc.IsSelective = true
 	} else {
 		p.unscan()
 	}
 
-	_, bc.Out, _ = p.scanParams()
+	_, bc.Out, _, _, err = p.scanParams("broadcast", bc.Name)
 
-	return bc
+	return bc, err
 }
 
-func (p *Parser) scanStruct() Struct {
-	str := Struct{}
-	_, lit := p.scanIgnoreWhitespace()
+func (p *Parser) scanStruct() (Struct, error) {
+	str := Struct{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "struct name")
+	if err != nil {
+		return str, err
+	}
 	str.Name = lit
 
-	str.Fields = p.scanStructParams()
+	str.Fields, err = p.scanStructParams()
+
+	return str, err
+}
+
+func (p *Parser) scanConstant() (Constant, error) {
+	constant := Constant{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "constant type")
+	if err != nil {
+		return constant, err
+	}
+	constant.Type = lit
+
+	lit, err = p.expect(lexer.IDENT, "constant name")
+	if err != nil {
+		return constant, err
+	}
+	constant.Name = lit
+
+	if _, err := p.expect(lexer.EQUALS, "= after constant "+constant.Name); err != nil {
+		return constant, err
+	}
+
+	tok, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case lexer.INTEGER, lexer.FLOAT, lexer.BOOLEAN:
+	case lexer.STRING:
+		lit = fmt.Sprintf("\"%s\"", lit)
+	default:
+		if err := p.malformed("expected value of constant %s but got %s", constant.Name, describe(tok, lit)); err != nil {
+			return constant, err
+		}
+	}
+	constant.Value = lit
 
-	return str
+	return constant, nil
 }
 
-func (p *Parser) scanTypeDefs() TypeDef {
-	typeDef := TypeDef{}
-	_, lit := p.scanIgnoreWhitespace()
+func (p *Parser) scanUnion() (Union, error) {
+	union := Union{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "union name")
+	if err != nil {
+		return union, err
+	}
+	union.Name = lit
+
+	union.Fields, err = p.scanStructParams()
+
+	return union, err
+}
+
+func (p *Parser) scanTypeDefs() (TypeDef, error) {
+	typeDef := TypeDef{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "typedef name")
+	if err != nil {
+		return typeDef, err
+	}
 	typeDef.Name = lit
 
-	// ignore "is" keyword
-	p.scanIgnoreWhitespace()
+	if err := p.expectKeyword("is"); err != nil {
+		return typeDef, err
+	}
 
-	_, lit = p.scanIgnoreWhitespace()
+	lit, err = p.expect(lexer.IDENT, "type of typedef "+typeDef.Name)
+	if err != nil {
+		return typeDef, err
+	}
 	typeDef.Type = lit
 
-	return typeDef
+	return typeDef, nil
 }
 
-func (p *Parser) scanArrayDefs() ArrayDef {
-	arrayDef := ArrayDef{}
-	_, lit := p.scanIgnoreWhitespace()
+func (p *Parser) scanArrayDefs() (ArrayDef, error) {
+	arrayDef := ArrayDef{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "array name")
+	if err != nil {
+		return arrayDef, err
+	}
 	arrayDef.Name = lit
 
-	// ignore "of" keyword
-	p.scanIgnoreWhitespace()
+	if err := p.expectKeyword("of"); err != nil {
+		return arrayDef, err
+	}
 
-	_, lit = p.scanIgnoreWhitespace()
+	lit, err = p.expect(lexer.IDENT, "element type of array "+arrayDef.Name)
+	if err != nil {
+		return arrayDef, err
+	}
 	arrayDef.Type = lit
 
-	return arrayDef
+	return arrayDef, nil
 }
 
-func (p *Parser) scanParam() Param {
+func (p *Parser) scanMapDef() (MapDef, error) {
+	mapDef := MapDef{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "map name")
+	if err != nil {
+		return mapDef, err
+	}
+	mapDef.Name = lit
+
+	if _, err := p.expect(lexer.CURLY_BRACKET_OPEN, "{ after map "+mapDef.Name); err != nil {
+		return mapDef, err
+	}
+
+	lit, err = p.expect(lexer.IDENT, "key type of map "+mapDef.Name)
+	if err != nil {
+		return mapDef, err
+	}
+	mapDef.KeyType = lit
+
+	if _, err := p.expect(lexer.TO, "to"); err != nil {
+		return mapDef, err
+	}
+
+	lit, err = p.expect(lexer.IDENT, "value type of map "+mapDef.Name)
+	if err != nil {
+		return mapDef, err
+	}
+	mapDef.ValueType = lit
+
+	if _, err := p.expect(lexer.CURLY_BRACKET_CLOSE, "} to close map "+mapDef.Name); err != nil {
+		return mapDef, err
+	}
+
+	return mapDef, nil
+}
+
+func (p *Parser) scanEnumeration() (Enumeration, error) {
+	enum := Enumeration{Pos: p.pos()}
+	lit, err := p.expect(lexer.IDENT, "enumeration name")
+	if err != nil {
+		return enum, err
+	}
+	enum.Name = lit
+
+	tok, _ := p.scanIgnoreWhitespace()
+	if tok == lexer.EXTENDS {
+		lit, err = p.expect(lexer.IDENT, "base enumeration of "+enum.Name)
+		if err != nil {
+			return enum, err
+		}
+		enum.Extends = lit
+	} else {
+		p.unscan()
+	}
+
+	enum.Enumerators, err = p.scanEnumerators()
+
+	return enum, err
+}
+
+func (p *Parser) scanEnumerators() ([]Enumerator, error) {
+	var enumerators []Enumerator
+
+	if _, err := p.expect(lexer.CURLY_BRACKET_OPEN, "{"); err != nil {
+		return enumerators, err
+	}
+
+	for {
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.COMMA {
+			continue
+		}
+
+		if tok == lexer.CURLY_BRACKET_CLOSE {
+			break
+		}
+
+		if tok == lexer.EOF {
+			if err := p.malformed("expected } to close enumerators but got %s", describe(tok, lit)); err != nil {
+				return enumerators, err
+			}
+			break
+		}
+
+		enumerator := Enumerator{}
+		if tok == lexer.DESCRIPTION {
+			enumerator.Description = lit
+			// scan enumerator name
+			tok, lit = p.scanIgnoreWhitespace()
+		}
+		enumerator.Pos = p.pos()
+
+		if err := p.check(tok, lit, lexer.IDENT, "enumerator name"); err != nil {
+			return enumerators, err
+		}
+		enumerator.Name = lit
+
+		tok, lit = p.scanIgnoreWhitespace()
+		if tok == lexer.EQUALS {
+			lit, err := p.expect(lexer.INTEGER, "value of enumerator "+enumerator.Name)
+			if err != nil {
+				return enumerators, err
+			}
+			enumerator.Value = lit
+		} else {
+			p.unscan()
+		}
+
+		enumerators = append(enumerators, enumerator)
+	}
+
+	return enumerators, nil
+}
+
+func (p *Parser) scanParam() (Param, error) {
 	param := Param{}
 
 	tok, lit := p.scanIgnoreWhitespace()
 	if tok == lexer.DESCRIPTION {
 		param.Description = lit
 		// scan param type
-		_, lit = p.scanIgnoreWhitespace()
+		tok, lit = p.scanIgnoreWhitespace()
 	}
 
+	param.Pos = p.pos()
+	if err := p.check(tok, lit, lexer.IDENT, "argument type"); err != nil {
+		return param, err
+	}
 	param.Type = lit
+
 	tok, lit = p.scanIgnoreWhitespace()
 	if tok == lexer.SQUARE_BRACKET_OPEN {
 		param.IsArray = true
-		// ignore SQUARE_BRACKET_CLOSE
-		p.scanIgnoreWhitespace()
+		if _, err := p.expect(lexer.SQUARE_BRACKET_CLOSE, "]"); err != nil {
+			return param, err
+		}
 
 		// scan param name
 		tok, lit = p.scanIgnoreWhitespace()
-		if tok == lexer.CIRCUMFLEX {
-			tok, lit = p.scanIgnoreWhitespace()
-			param.Name = fmt.Sprintf("^%s", lit)
-		} else {
-			param.Name = lit
-		}
+	}
 
-	} else {
-		if tok == lexer.CIRCUMFLEX {
-			tok, lit = p.scanIgnoreWhitespace()
-			param.Name = fmt.Sprintf("^%s", lit)
-		} else {
-			param.Name = lit
-		}
+	prefix := ""
+	if tok == lexer.CIRCUMFLEX {
+		prefix = "^"
+		tok, lit = p.scanIgnoreWhitespace()
+	}
+
+	if err := p.check(tok, lit, lexer.IDENT, "argument name"); err != nil {
+		return param, err
 	}
+	param.Name = prefix + lit
 
-	return param
+	return param, nil
 }
 
-func (p *Parser) scanParams() ([]Param, []Param, bool) {
+// scanParams scans the blocks of the method or broadcast kind name. Broadcasts
+// only have out parameters, every block may be declared once.
+func (p *Parser) scanParams(kind, name string) ([]Param, []Param, *MethodError, bool, error) {
 	var inParams []Param
 	var outParams []Param
+	var methodError *MethodError
 	fireAndForget := false
-	for {
-		tok, _ := p.scanIgnoreWhitespace()
-		if tok == lexer.CURLY_BRACKET_OPEN {
-			continue
+	broadcast := kind == "broadcast"
+	seen := map[lexer.Token]bool{}
+
+	// scanFireAndForget handles the fireAndForget modifier before or inside
+	// the block
+	scanFireAndForget := func() error {
+		if broadcast {
+			return p.malformed("broadcast %s can't be fireAndForget", name)
 		}
+		if fireAndForget {
+			return p.malformed("fireAndForget declared twice in method %s", name)
+		}
+		fireAndForget = true
+		return nil
+	}
 
+	// once rejects blocks which are declared more than once
+	once := func(tok lexer.Token, lit string) error {
+		if seen[tok] {
+			return p.malformed("%s declared twice in %s %s", lit, kind, name)
+		}
+		seen[tok] = true
+		return nil
+	}
+
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok == lexer.FIRE_AND_FORGET {
+		if err := scanFireAndForget(); err != nil {
+			return inParams, outParams, methodError, fireAndForget, err
+		}
+		tok, lit = p.scanIgnoreWhitespace()
+	}
+
+	if err := p.check(tok, lit, lexer.CURLY_BRACKET_OPEN, "{"); err != nil {
+		return inParams, outParams, methodError, fireAndForget, err
+	}
+
+	for {
+		tok, lit := p.scanIgnoreWhitespace()
 		if tok == lexer.FIRE_AND_FORGET {
-			fireAndForget = true
+			if err := scanFireAndForget(); err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
+			}
 			continue
 		}
 
 		if tok == lexer.IN {
-			for {
-				tok, _ = p.scanIgnoreWhitespace()
-				if tok == lexer.CURLY_BRACKET_OPEN {
-					continue
-				}
-
-				if tok == lexer.CURLY_BRACKET_CLOSE {
-					break
+			if broadcast {
+				if err := p.malformed("broadcast %s can't have in parameters", name); err != nil {
+					return inParams, outParams, methodError, fireAndForget, err
 				}
+			} else if err := once(tok, lit); err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
+			}
 
-				p.unscan()
-				param := p.scanParam()
-				inParams = append(inParams, param)
+			params, err := p.scanStructParams()
+			if err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
 			}
 
+			if !broadcast {
+				inParams = append(inParams, params...)
+			}
 			continue
 		}
 
 		if !fireAndForget && tok == lexer.OUT {
-			for {
-				tok, _ = p.scanIgnoreWhitespace()
-				if tok == lexer.CURLY_BRACKET_OPEN {
-					continue
-				}
+			if err := once(tok, lit); err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
+			}
+
+			params, err := p.scanStructParams()
+			if err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
+			}
+
+			outParams = append(outParams, params...)
+			continue
+		}
 
-				if tok == lexer.CURLY_BRACKET_CLOSE {
-					break
+		if tok == lexer.ERROR {
+			if broadcast {
+				if err := p.malformed("broadcast %s can't declare errors", name); err != nil {
+					return inParams, outParams, methodError, fireAndForget, err
 				}
+			} else if err := once(tok, lit); err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
+			}
 
-				p.unscan()
-				param := p.scanParam()
-				outParams = append(outParams, param)
+			scanned, err := p.scanMethodError()
+			if err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
 			}
 
+			if !broadcast {
+				methodError = scanned
+			}
 			continue
 		}
 
+		if tok != lexer.CURLY_BRACKET_CLOSE {
+			expected := "in, out, error or }"
+			if broadcast {
+				expected = "out or }"
+			} else if fireAndForget {
+				expected = "in, error or }"
+			}
+			if err := p.malformed("expected %s but got %s", expected, describe(tok, lit)); err != nil {
+				return inParams, outParams, methodError, fireAndForget, err
+			}
+		}
+
 		break
 	}
 
-	return inParams, outParams, fireAndForget
+	return inParams, outParams, methodError, fireAndForget, nil
 }
 
-func (p *Parser) scanStructParams() []Param {
+// scanMethodError scans "error SomeEnum" as well as inline error
+// enumerations like "error { A B }" or "error extends SomeEnum { C }".
+func (p *Parser) scanMethodError() (*MethodError, error) {
+	methodError := &MethodError{Pos: p.pos()}
+
+	tok, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case lexer.IDENT:
+		methodError.Type = lit
+		return methodError, nil
+	case lexer.EXTENDS:
+		lit, err := p.expect(lexer.IDENT, "base enumeration of error")
+		if err != nil {
+			return methodError, err
+		}
+		methodError.Extends = lit
+	default:
+		p.unscan()
+	}
+
+	var err error
+	methodError.Enumerators, err = p.scanEnumerators()
+
+	return methodError, err
+}
+
+// scanStructParams scans a block of arguments or fields enclosed in braces.
+func (p *Parser) scanStructParams() ([]Param, error) {
 	var params []Param
 
+	if _, err := p.expect(lexer.CURLY_BRACKET_OPEN, "{"); err != nil {
+		return params, err
+	}
+
 	for {
-		tok, _ := p.scanIgnoreWhitespace()
-		if tok == lexer.CURLY_BRACKET_OPEN {
-			continue
+		tok, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.CURLY_BRACKET_CLOSE {
+			break
 		}
 
-		if tok == lexer.CURLY_BRACKET_CLOSE {
+		if tok == lexer.EOF {
+			if err := p.malformed("expected } but got %s", describe(tok, lit)); err != nil {
+				return params, err
+			}
 			break
 		}
 
 		p.unscan()
-		param := p.scanParam()
+		param, err := p.scanParam()
+		if err != nil {
+			return params, err
+		}
 		params = append(params, param)
 	}
 
-	return params
+	return params, nil
 }
 
 // scan returns the next token from the underlying scanner.
@@ -533,21 +1260,216 @@ func (p *Parser) scan() (tok lexer.Token, lit string) {
 	}
 
 	// Otherwise read the next token from the scanner.
-	tok, lit = p.s.Scan()
+	tok, lit, pos := p.s.Scan()
 
 	// Save it to the buffer in case we unscan later.
-	p.buf.tok, p.buf.lit = tok, lit
+	p.buf.tok, p.buf.lit, p.buf.pos = tok, lit, pos
+
+	switch tok {
+	case lexer.CURLY_BRACKET_OPEN:
+		p.depth++
+	case lexer.CURLY_BRACKET_CLOSE:
+		// a stray bracket on file level doesn't close anything
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
 
 	return
 }
 
+// pos returns the position of the last read token.
+func (p *Parser) pos() lexer.Position { return p.buf.pos }
+
+// errorf returns a ParseError at pos.
+func (p *Parser) errorf(pos lexer.Position, format string, args ...interface{}) *ParseError {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...), Line: sourceLine(p.src, pos.Line)}
+}
+
+// malformed reports malformed input at the last read token. In lenient mode
+// it is recorded as warning and nil is returned, so parsing goes on.
+func (p *Parser) malformed(format string, args ...interface{}) error {
+	err := p.errorf(p.pos(), format, args...)
+	if p.Mode != Lenient {
+		return err
+	}
+
+	p.warn(err)
+
+	return nil
+}
+
+// errDropped is returned in lenient mode if a declaration lacks a required
+// name, type or value. The warning is recorded already, the callers recover
+// from it like from an error, so the declaration is skipped as a whole.
+var errDropped = errors.New("declaration dropped")
+
+// drop reports the last read token as malformed. In lenient mode it is
+// recorded as warning and errDropped is returned.
+func (p *Parser) drop(format string, args ...interface{}) error {
+	if err := p.malformed(format, args...); err != nil {
+		return err
+	}
+
+	return errDropped
+}
+
+// warn records a warning unless the recovery from an earlier one started at
+// the same token. A skipped token is read again by the enclosing parser and
+// would be reported once more.
+func (p *Parser) warn(warning *ParseError) {
+	for _, recovery := range p.recoveries {
+		if recovery == warning.Pos {
+			return
+		}
+	}
+
+	p.Warnings = append(p.Warnings, warning)
+	p.recoveries = append(p.recoveries, warning.Pos)
+}
+
+// recoverFrom records err and skips the input up to the next of the
+// declarations at depth, so parsing can go on after a syntax error. A closing
+// curly bracket ending a block at depth ends the broken declaration, one
+// ending the enclosing block is left for its parser. See skipTo.
+func (p *Parser) recoverFrom(err error, depth int, declarations map[lexer.Token]bool) {
+	if !errors.Is(err, errDropped) {
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			parseErr = p.errorf(p.pos(), "%v", err)
+		}
+		p.errors = append(p.errors, parseErr)
+	}
+
+	// the token the error was found at could start the next declaration
+	p.unscan()
+	p.skipTo(depth, declarations)
+}
+
+// skipTo skips the input up to the next of the declarations at depth. Blocks
+// opened by the skipped input are skipped as a whole.
+func (p *Parser) skipTo(depth int, declarations map[lexer.Token]bool) {
+	for {
+		tok, _ := p.scanIgnoreWhitespace()
+		switch {
+		case tok == lexer.EOF:
+			return
+		case tok == lexer.CURLY_BRACKET_CLOSE && p.depth == depth:
+			return
+		case tok == lexer.CURLY_BRACKET_CLOSE && p.depth < depth:
+			p.unscan()
+			return
+		case declarations[tok] && p.depth == depth:
+			p.unscan()
+			return
+		}
+	}
+}
+
+// Tokens starting the declarations of a file, a type collection and an
+// interface.
+var (
+	fileDeclarations = map[lexer.Token]bool{
+		lexer.INTERFACE:       true,
+		lexer.TYPE_COLLECTION: true,
+	}
+	typeDeclarations = map[lexer.Token]bool{
+		lexer.STRUCT:      true,
+		lexer.TYPEDEF:     true,
+		lexer.ARRAYDEF:    true,
+		lexer.ENUMERATION: true,
+		lexer.MAP:         true,
+		lexer.UNION:       true,
+		lexer.CONST:       true,
+	}
+	interfaceDeclarations = map[lexer.Token]bool{
+		lexer.ATTRIBUTE:   true,
+		lexer.METHOD:      true,
+		lexer.BROADCAST:   true,
+		lexer.STRUCT:      true,
+		lexer.TYPEDEF:     true,
+		lexer.ARRAYDEF:    true,
+		lexer.ENUMERATION: true,
+		lexer.MAP:         true,
+		lexer.UNION:       true,
+		lexer.CONST:       true,
+	}
+)
+
+// check reports the last read token as malformed unless it is want. In
+// lenient mode a missing name, value or file name drops the declaration,
+// missing punctuation is assumed and the token is parsed again.
+func (p *Parser) check(tok lexer.Token, lit string, want lexer.Token, what string) error {
+	if tok == want {
+		return nil
+	}
+
+	switch want {
+	case lexer.IDENT, lexer.INTEGER, lexer.STRING:
+		return p.drop("expected %s but got %s", what, describe(tok, lit))
+	}
+
+	if err := p.malformed("expected %s but got %s", what, describe(tok, lit)); err != nil {
+		return err
+	}
+
+	p.unscan()
+	switch want {
+	case lexer.CURLY_BRACKET_OPEN:
+		// the block is treated as opened, so its } doesn't close the enclosing one
+		p.depth++
+	case lexer.CURLY_BRACKET_CLOSE:
+		// the block is treated as closed
+		p.depth--
+	}
+
+	return nil
+}
+
+// expect scans the next token and reports it as malformed unless it is want.
+func (p *Parser) expect(want lexer.Token, what string) (string, error) {
+	tok, lit := p.scanIgnoreWhitespace()
+	return lit, p.check(tok, lit, want, what)
+}
+
+// expectKeyword scans the next token and reports it as malformed unless it
+// is keyword. Used for words like "is" which the lexer returns as IDENT.
+func (p *Parser) expectKeyword(keyword string) error {
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok == lexer.IDENT && lit == keyword {
+		return nil
+	}
+
+	if err := p.malformed("expected %s but got %s", keyword, describe(tok, lit)); err != nil {
+		return err
+	}
+
+	// the keyword is assumed, the token is parsed again
+	p.unscan()
+	return nil
+}
+
+// describe returns a token for use in error messages.
+func describe(tok lexer.Token, lit string) string {
+	if tok == lexer.EOF {
+		return "end of file"
+	} else if tok == lexer.ILLEGAL && lit == "/*" {
+		return "unterminated comment"
+	} else if tok == lexer.ILLEGAL && lit == "<**" {
+		return "unterminated description"
+	}
+
+	return fmt.Sprintf("%q", lit)
+}
+
 // unscan pushes the previously read token back onto the buffer.
 func (p *Parser) unscan() { p.buf.n = 1 }
 
-// scanIgnoreWhitespace scans the next non-whitespace token.
+// scanIgnoreWhitespace scans the next token which is neither whitespace
+// nor a comment.
 func (p *Parser) scanIgnoreWhitespace() (tok lexer.Token, lit string) {
 	tok, lit = p.scan()
-	if tok == lexer.WHITESPACE {
+	for tok == lexer.WHITESPACE || tok == lexer.COMMENT {
 		tok, lit = p.scan()
 	}
 
@@ -2,7 +2,10 @@ package pkg
 
 import (
 	"bytes"
+	"errors"
 	"github.com/SourceFellows/go-fidl-dbus-generator/examples"
+	"github.com/SourceFellows/go-fidl-dbus-generator/pkg/lexer"
+	"strings"
 	"testing"
 )
 
@@ -52,6 +55,16 @@ func TestParseFidl_SystemManager(t *testing.T) {
 		return
 	}
 
+	iface := fidl.Interfaces[0]
+	if iface.MajorVersion != 0 || iface.MinorVersion != 1 {
+		t.Errorf("got wrong version %d.%d", iface.MajorVersion, iface.MinorVersion)
+	}
+
+	if len(iface.Attributes) != 32 || len(iface.Methods) != 41 || len(iface.Broadcasts) != 6 {
+		t.Errorf("got wrong number of members: %d attributes, %d methods, %d broadcasts",
+			len(iface.Attributes), len(iface.Methods), len(iface.Broadcasts))
+	}
+
 }
 
 func TestParseFidl_FireAndForget(t *testing.T) {
@@ -73,12 +86,12 @@ func TestParseFidl_FireAndForget(t *testing.T) {
 		return
 	}
 
-	if len(fidl.Methods) != 1 {
-		t.Errorf("wrong number of methods. expected 1 but got %d", len(fidl.Methods))
+	if len(fidl.Interfaces[0].Methods) != 1 {
+		t.Errorf("wrong number of methods. expected 1 but got %d", len(fidl.Interfaces[0].Methods))
 		return
 	}
 
-	if !fidl.Methods[0].FireAndForget {
+	if !fidl.Interfaces[0].Methods[0].FireAndForget {
 		t.Error("expected fireAndForget method")
 		return
 	}
@@ -87,10 +100,12 @@ func TestParseFidl_FireAndForget(t *testing.T) {
 
 func paramOfName(fidl *Fidl, name string) Param {
 
-	for _, tr := range fidl.Methods {
-		for _, p := range tr.In {
-			if p.Name == name {
-				return p
+	for _, iface := range fidl.Interfaces {
+		for _, tr := range iface.Methods {
+			for _, p := range tr.In {
+				if p.Name == name {
+					return p
+				}
 			}
 		}
 	}
@@ -98,3 +113,814 @@ func paramOfName(fidl *Fidl, name string) Param {
 	return Param{}
 
 }
+
+func TestParseFidl_Enumerations(t *testing.T) {
+
+	//given
+	parser := NewParser(bytes.NewReader(examples.EnumerationsFidl))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	if len(fidl.Interfaces[0].Enumerations) != 2 {
+		t.Errorf("wrong number of enumerations. expected 2 but got %d", len(fidl.Interfaces[0].Enumerations))
+		return
+	}
+
+	extended := fidl.Interfaces[0].Enumerations[1]
+	if extended.Extends != "Color" {
+		t.Errorf("expected ExtendedColor to extend Color but got %q", extended.Extends)
+	}
+
+	if len(extended.Enumerators) != 3 {
+		t.Errorf("wrong number of enumerators. expected 3 but got %d", len(extended.Enumerators))
+		return
+	}
+
+	if extended.Enumerators[1].Value != "0x10" {
+		t.Errorf("expected value 0x10 but got %q", extended.Enumerators[1].Value)
+	}
+
+	if extended.Enumerators[0].Description == "" {
+		t.Error("expected enumerator description")
+	}
+
+}
+
+func TestParseFidl_Maps(t *testing.T) {
+
+	//given
+	parser := NewParser(bytes.NewReader(examples.MapsFidl))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	if len(fidl.Interfaces[0].Maps) != 2 {
+		t.Errorf("wrong number of maps. expected 2 but got %d", len(fidl.Interfaces[0].Maps))
+		return
+	}
+
+	expected := []MapDef{
+		{Description: " @description: Options by name. ", Name: "Options", KeyType: "String", ValueType: "UInt32"},
+		{Name: "ScopedOptions", KeyType: "String", ValueType: "Options"},
+	}
+	for i, mapDef := range fidl.Interfaces[0].Maps {
+		mapDef.Pos = lexer.Position{}
+		if mapDef != expected[i] {
+			t.Errorf("got wrong map. expected %+v but got %+v", expected[i], mapDef)
+		}
+	}
+
+	if len(fidl.Interfaces[0].Structs) != 1 || len(fidl.Interfaces[0].Structs[0].Fields) != 2 {
+		t.Error("expected struct Settings with two fields after the maps")
+	}
+
+}
+
+func TestParseFidl_Unions(t *testing.T) {
+
+	//given
+	parser := NewParser(bytes.NewReader(examples.UnionsFidl))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	if len(fidl.Interfaces[0].Unions) != 1 {
+		t.Errorf("wrong number of unions. expected 1 but got %d", len(fidl.Interfaces[0].Unions))
+		return
+	}
+
+	union := fidl.Interfaces[0].Unions[0]
+	if union.Name != "Value" || len(union.Fields) != 4 {
+		t.Errorf("expected union Value with 4 alternatives but got %+v", union)
+		return
+	}
+
+	if !union.Fields[3].IsArray {
+		t.Error("alternative list should be an array")
+	}
+
+	if len(fidl.Interfaces[0].Structs) != 2 {
+		t.Errorf("wrong number of structs. expected 2 but got %d", len(fidl.Interfaces[0].Structs))
+	}
+
+}
+
+func TestParseFidl_Constants(t *testing.T) {
+
+	//given
+	parser := NewParser(bytes.NewReader(examples.ConstantsFidl))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	expected := []Constant{
+		{Description: " @description: Default timeout in milliseconds. ", Type: "UInt32", Name: "DefaultTimeout", Value: "25000"},
+		{Type: "Int16", Name: "Offset", Value: "-12"},
+		{Type: "UInt8", Name: "FlagMask", Value: "0x0F"},
+		{Type: "Boolean", Name: "Enabled", Value: "true"},
+		{Type: "String", Name: "Greeting", Value: `"hello \"world\""`},
+		{Type: "Float", Name: "Ratio", Value: "0.5f"},
+		{Type: "Double", Name: "Epsilon", Value: "1e-9"},
+	}
+
+	if len(fidl.Interfaces[0].Constants) != len(expected) {
+		t.Errorf("wrong number of constants. expected %d but got %d", len(expected), len(fidl.Interfaces[0].Constants))
+		return
+	}
+
+	for i, constant := range fidl.Interfaces[0].Constants {
+		constant.Pos = lexer.Position{}
+		if constant != expected[i] {
+			t.Errorf("got wrong constant. expected %+v but got %+v", expected[i], constant)
+		}
+	}
+
+	if len(fidl.Interfaces[0].Methods) != 1 {
+		t.Errorf("wrong number of methods. expected 1 but got %d", len(fidl.Interfaces[0].Methods))
+	}
+
+}
+
+func TestParseFidl_TypeCollections(t *testing.T) {
+
+	//given
+	parser := NewParser(bytes.NewReader(examples.TypeCollectionsFidl))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	if len(fidl.TypeCollections) != 2 {
+		t.Errorf("wrong number of type collections. expected 2 but got %d", len(fidl.TypeCollections))
+		return
+	}
+
+	common := fidl.TypeCollections[0]
+	if common.Name != "CommonTypes" || common.MajorVersion != 1 || common.Description == "" {
+		t.Errorf("got wrong type collection %+v", common)
+	}
+
+	if len(common.Structs) != 1 || len(common.Enumerations) != 1 {
+		t.Errorf("expected one struct and one enumeration in CommonTypes but got %+v", common.Types)
+	}
+
+	if fidl.TypeCollections[1].Name != "" || len(fidl.TypeCollections[1].TypeDefs) != 1 {
+		t.Errorf("expected anonymous type collection with one typedef but got %+v", fidl.TypeCollections[1])
+	}
+
+	if len(fidl.Interfaces) != 2 {
+		t.Errorf("wrong number of interfaces. expected 2 but got %d", len(fidl.Interfaces))
+		return
+	}
+
+	writer := fidl.Interfaces[1]
+	if writer.Name != "Writer" || len(writer.Methods) != 1 || len(writer.Broadcasts) != 1 {
+		t.Errorf("got wrong interface %+v", writer)
+	}
+
+}
+
+func TestParseFidl_Inheritance(t *testing.T) {
+
+	//given
+	parser := NewParser(bytes.NewReader(examples.InheritanceFidl))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	if len(fidl.Interfaces) != 2 {
+		t.Errorf("wrong number of interfaces. expected 2 but got %d", len(fidl.Interfaces))
+		return
+	}
+
+	if fidl.Interfaces[0].Extends != "" {
+		t.Errorf("base interface must not extend anything but got %q", fidl.Interfaces[0].Extends)
+	}
+
+	printer := fidl.Interfaces[1]
+	if printer.Name != "Printer" || printer.Extends != "Device" || printer.MajorVersion != 1 {
+		t.Errorf("got wrong interface info %+v", printer.InterfaceInfo)
+	}
+
+	if len(printer.Methods) != 1 || len(printer.Broadcasts) != 2 || !printer.Broadcasts[1].IsSelective {
+		t.Errorf("inherited members must not be added by the parser but got %+v", printer)
+	}
+
+}
+
+func TestParseFidl_MethodErrors(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`package org.example
+interface Service {
+	method Inline {
+		in {
+			String name
+		}
+		error {
+			NotFound
+			Denied = 3
+		}
+	}
+	method Reference {
+		error Errors
+	}
+	method Extended {
+		out {
+			String value
+		}
+		error extends Errors {
+			Busy
+		}
+	}
+	method NoErrors {
+	}
+}`))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	methods := fidl.Interfaces[0].Methods
+	if len(methods) != 4 {
+		t.Errorf("wrong number of methods. expected 4 but got %d", len(methods))
+		return
+	}
+
+	inline := methods[0].Error
+	if inline == nil || len(inline.Enumerators) != 2 || inline.Enumerators[1].Value != "3" || len(methods[0].In) != 1 {
+		t.Errorf("got wrong inline errors %+v", methods[0])
+	}
+
+	if methods[1].Error == nil || methods[1].Error.Type != "Errors" {
+		t.Errorf("got wrong error reference %+v", methods[1].Error)
+	}
+
+	extended := methods[2].Error
+	if extended == nil || extended.Extends != "Errors" || len(extended.Enumerators) != 1 || len(methods[2].Out) != 1 {
+		t.Errorf("got wrong extended errors %+v", methods[2])
+	}
+
+	if methods[3].Error != nil {
+		t.Errorf("expected no errors but got %+v", methods[3].Error)
+	}
+
+}
+
+func TestParseFidl_AttributeModifiers(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`package org.example
+interface Service {
+	attribute String plain
+	attribute String fixed readonly
+	attribute UInt32[] counters noSubscriptions
+	attribute UInt32 both noSubscriptions readonly
+	method Reset {
+	}
+}`))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	expected := []Attribute{
+		{Type: "String", Name: "plain"},
+		{Type: "String", Name: "fixed", ReadOnly: true},
+		{Type: "UInt32", IsArray: true, Name: "counters", NoSubscriptions: true},
+		{Type: "UInt32", Name: "both", ReadOnly: true, NoSubscriptions: true},
+	}
+
+	attributes := fidl.Interfaces[0].Attributes
+	if len(attributes) != len(expected) {
+		t.Errorf("wrong number of attributes. expected %d but got %d", len(expected), len(attributes))
+		return
+	}
+
+	for i, attribute := range attributes {
+		attribute.Pos = lexer.Position{}
+		if attribute != expected[i] {
+			t.Errorf("got wrong attribute. expected %+v but got %+v", expected[i], attribute)
+		}
+	}
+
+	if len(fidl.Interfaces[0].Methods) != 1 {
+		t.Errorf("wrong number of methods. expected 1 but got %d", len(fidl.Interfaces[0].Methods))
+	}
+
+}
+
+func TestParseFidl_Comments(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`// the package
+package test /* trailing */
+
+/* interface Ignored {
+	method Reset { }
+} */
+interface Device {
+	// method Hidden { in { String x } }
+	attribute String name // } closes nothing
+	method Reset /* fireAndForget */ {
+		in {
+			UInt32 delay // in milliseconds
+		}
+	}
+}`))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	if len(fidl.Interfaces) != 1 || fidl.Interfaces[0].Name != "Device" {
+		t.Errorf("expected only interface Device but got %v", fidl.Interfaces)
+		return
+	}
+
+	iface := fidl.Interfaces[0]
+	if len(iface.Attributes) != 1 || len(iface.Methods) != 1 {
+		t.Errorf("expected 1 attribute and 1 method but got %v and %v", iface.Attributes, iface.Methods)
+		return
+	}
+
+	method := iface.Methods[0]
+	if method.Name != "Reset" || method.FireAndForget || len(method.In) != 1 || method.In[0].Name != "delay" {
+		t.Errorf("got wrong method %v", method)
+	}
+
+}
+
+func TestParseFidl_Positions(t *testing.T) {
+
+	//given
+	parser := NewFileParser("test.fidl", strings.NewReader(`package test
+
+interface Device {
+	version { major 1 minor 0 }
+	attribute String name
+	method Reset {
+		in {
+			UInt32 delay
+		}
+	}
+	broadcast Changed {
+		out {
+			String name
+		}
+	}
+}`))
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	iface := fidl.Interfaces[0]
+	positions := []struct {
+		node     string
+		expected string
+		got      lexer.Position
+	}{
+		{"package", "test.fidl:1:1", fidl.PackageInfo.Pos},
+		{"interface", "test.fidl:3:1", iface.Pos},
+		{"attribute", "test.fidl:5:2", iface.Attributes[0].Pos},
+		{"method", "test.fidl:6:2", iface.Methods[0].Pos},
+		{"in param", "test.fidl:8:4", iface.Methods[0].In[0].Pos},
+		{"broadcast", "test.fidl:11:2", iface.Broadcasts[0].Pos},
+		{"out param", "test.fidl:13:4", iface.Broadcasts[0].Out[0].Pos},
+	}
+	for _, position := range positions {
+		if position.got.String() != position.expected {
+			t.Errorf("wrong position of %s. expected %s but got %s", position.node, position.expected, position.got)
+		}
+	}
+
+}
+
+func TestParseError(t *testing.T) {
+
+	//given
+	parser := NewFileParser("broken.fidl", strings.NewReader(`package test
+
+interface Device extends {
+}`))
+
+	//when
+	_, err := parser.Parse()
+
+	//then
+	var errs ErrorList
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf("expected one ParseError but got %v", err)
+		return
+	}
+
+	parseErr := errs[0]
+
+	if parseErr.Pos.String() != "broken.fidl:3:26" {
+		t.Errorf("wrong error position. expected broken.fidl:3:26 but got %s", parseErr.Pos)
+	}
+
+	expected := `broken.fidl:3:26: expected base interface name of Device but got "{"
+interface Device extends {
+                         ^`
+	if parseErr.Snippet() != expected {
+		t.Errorf("wrong snippet. expected\n%s\nbut got\n%s", expected, parseErr.Snippet())
+	}
+
+}
+
+func TestParseFidl_Strict(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		fidl     string
+		expected string
+	}{
+		{"unknown keyword", "package test\ninterface Device {\n\tmethd Reset {\n\t}\n}", `3:2: expected attribute, method, broadcast or type definition in interface Device but got "methd"`},
+		{"missing is", "package test\ninterface Device {\n\ttypedef Id UInt32\n}", `3:13: expected is but got "UInt32"`},
+		{"missing of", "package test\ninterface Device {\n\tarray Ids UInt32\n}", `3:12: expected of but got "UInt32"`},
+		{"missing map to", "package test\ninterface Device {\n\tmap Ids { String UInt32 }\n}", `3:19: expected to but got "UInt32"`},
+		{"missing constant =", "package test\ninterface Device {\n\tconst UInt32 Timeout 5\n}", `3:23: expected = after constant Timeout but got "5"`},
+		{"missing attribute name", "package test\ninterface Device {\n\tattribute UInt32\n}", `4:1: expected attribute name but got "}"`},
+		{"unclosed interface", "package test\ninterface Device {\n\tattribute UInt32 id\n", `4:1: expected } to close interface Device but got end of file`},
+		{"unknown top level input", "package test\ninterfac Device {\n}", `2:1: expected interface or typeCollection but got "interfac"`},
+		{"broadcast in", "package test\ninterface Device {\n\tbroadcast Changed {\n\t\tin { String name }\n\t}\n}", `4:3: broadcast Changed can't have in parameters`},
+		{"broadcast error", "package test\ninterface Device {\n\tbroadcast Changed {\n\t\terror { Failed }\n\t}\n}", `4:3: broadcast Changed can't declare errors`},
+		{"broadcast fireAndForget", "package test\ninterface Device {\n\tbroadcast Changed fireAndForget {\n\t}\n}", `3:20: broadcast Changed can't be fireAndForget`},
+		{"duplicate in", "package test\ninterface Device {\n\tmethod Reset {\n\t\tin { UInt32 a }\n\t\tin { UInt32 b }\n\t}\n}", `5:3: in declared twice in method Reset`},
+		{"duplicate out", "package test\ninterface Device {\n\tbroadcast Changed {\n\t\tout { UInt32 a }\n\t\tout { UInt32 b }\n\t}\n}", `5:3: out declared twice in broadcast Changed`},
+		{"duplicate fireAndForget", "package test\ninterface Device {\n\tmethod Reset fireAndForget {\n\t\tfireAndForget\n\t}\n}", `4:3: fireAndForget declared twice in method Reset`},
+		{"duplicate modifier", "package test\ninterface Device {\n\tattribute UInt32 id readonly readonly\n}", `3:31: modifier readonly declared twice for attribute id`},
+		{"unterminated comment", "package test\ninterface Device {\n}\n/* never closed", `4:1: expected interface or typeCollection but got unterminated comment`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			//given
+			parser := NewParser(strings.NewReader(test.fidl))
+
+			//when
+			_, err := parser.Parse()
+
+			//then
+			if err == nil {
+				t.Errorf("expected error %s but got none", test.expected)
+				return
+			}
+
+			if err.Error() != test.expected {
+				t.Errorf("wrong error. expected %s but got %s", test.expected, err)
+			}
+		})
+	}
+
+}
+
+func TestParseFidl_Lenient(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`package test
+interface Device {
+	version major 1 minor 0 }
+	attribute UInt32 counter
+	methd Reset
+}`))
+	parser.Mode = Lenient
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	var warnings []string
+	for _, warning := range parser.Warnings {
+		warnings = append(warnings, warning.Error())
+	}
+
+	expected := []string{
+		`3:10: expected { after version but got "major"`,
+		`5:2: expected attribute, method, broadcast or type definition in interface Device but got "methd"`,
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong warnings. expected\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(warnings, "\n"))
+	}
+
+	iface := fidl.Interfaces[0]
+	if iface.MajorVersion != 1 || len(iface.Attributes) != 1 || iface.Methods != nil {
+		t.Errorf("expected version 1 and the attribute to be parsed but got %+v", iface)
+	}
+
+}
+
+func TestParseFidl_LenientUnknownDeclarations(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`package test
+interfac Ignored {
+	method Reset { }
+}
+interface Device {
+	methd Foo { in { String x } }
+	attribute UInt32 counter
+	method Reset {
+		in {
+			UInt32 delay
+		}
+	}
+}
+typeCollection Types {
+	strct Point { Int32 x }
+	typedef Id is UInt32
+}`))
+	parser.Mode = Lenient
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	var warnings []string
+	for _, warning := range parser.Warnings {
+		warnings = append(warnings, warning.Error())
+	}
+
+	expected := []string{
+		`2:1: expected interface or typeCollection but got "interfac"`,
+		`6:2: expected attribute, method, broadcast or type definition in interface Device but got "methd"`,
+		`15:2: expected type definition in typeCollection Types but got "strct"`,
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong warnings. expected\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(warnings, "\n"))
+	}
+
+	if len(fidl.Interfaces) != 1 {
+		t.Errorf("expected only interface Device but got %+v", fidl.Interfaces)
+		return
+	}
+
+	iface := fidl.Interfaces[0]
+	if len(iface.Attributes) != 1 || len(iface.Methods) != 1 || len(iface.Methods[0].In) != 1 {
+		t.Errorf("expected the members after the unknown declaration to be parsed but got %+v", iface)
+	}
+
+	if len(fidl.TypeCollections) != 1 || len(fidl.TypeCollections[0].TypeDefs) != 1 || fidl.TypeCollections[0].Structs != nil {
+		t.Errorf("expected only typedef Id in typeCollection Types but got %+v", fidl.TypeCollections)
+	}
+
+}
+
+func TestParseFidl_LenientMissingNames(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		fidl     string
+		expected string
+	}{
+		{"truncated attribute", "package test\ninterface Device {\n\tattribute UInt32", `3:18: expected attribute name but got end of file`},
+		{"missing argument name", "package test\ninterface Device {\n\tmethod Reset { in { UInt32 ^ } }\n\tattribute UInt32 id\n}", `3:31: expected argument name but got "}"`},
+		{"missing method name", "package test\ninterface Device {\n\tmethod { in { UInt32 delay } }\n\tattribute UInt32 id\n}", `3:9: expected method name but got "{"`},
+		{"missing field name", "package test\ninterface Device {\n\tstruct Point { Int32 } attribute UInt32 id\n}", `3:23: expected argument name but got "}"`},
+		{"missing enumerator value", "package test\ninterface Device {\n\tenumeration Level { LOW = HIGH } attribute UInt32 id\n}", `3:28: expected value of enumerator LOW but got "HIGH"`},
+		{"missing import name", "package test\nimport \"Types.fidl\"\ninterface Device {\n\tattribute UInt32 id\n}", `2:8: expected imported package or model name but got "Types.fidl"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			//given
+			parser := NewParser(strings.NewReader(test.fidl))
+			parser.Mode = Lenient
+
+			//when
+			fidl, err := parser.Parse()
+
+			//then
+			if err != nil {
+				t.Errorf("could not parse fidl because of: %v", err)
+				return
+			}
+
+			var warnings []string
+			for _, warning := range parser.Warnings {
+				warnings = append(warnings, warning.Error())
+			}
+
+			if strings.Join(warnings, "\n") != test.expected {
+				t.Errorf("wrong warnings. expected\n%s\nbut got\n%s", test.expected, strings.Join(warnings, "\n"))
+			}
+
+			iface := fidl.Interfaces[0]
+			if len(iface.Methods) != 0 || len(iface.Structs) != 0 || len(iface.Enumerations) != 0 {
+				t.Errorf("expected the broken declaration to be dropped but got %+v", iface)
+			}
+
+			fidl.TargetPackage = "test"
+			for _, writer := range []WriterType{SenderWriter, ReceiverWriter, ServerWriter} {
+				if err := Write(fidl, writer, &bytes.Buffer{}); err != nil {
+					t.Errorf("could not write fidl because of: %v", err)
+				}
+			}
+		})
+	}
+
+}
+
+func TestParseFidl_LenientWarningsOnOneLine(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`package test
+interface Device {
+	attribute UInt32 id readonly readonly attribute UInt32 serial readonly readonly
+	typedef Id UInt32 typedef Serial UInt32
+}`))
+	parser.Mode = Lenient
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	var warnings []string
+	for _, warning := range parser.Warnings {
+		warnings = append(warnings, warning.Error())
+	}
+
+	expected := []string{
+		`3:31: modifier readonly declared twice for attribute id`,
+		`3:73: modifier readonly declared twice for attribute serial`,
+		`4:13: expected is but got "UInt32"`,
+		`4:35: expected is but got "UInt32"`,
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong warnings. expected\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(warnings, "\n"))
+	}
+
+	iface := fidl.Interfaces[0]
+	if len(iface.Attributes) != 2 || len(iface.TypeDefs) != 2 || iface.TypeDefs[1].Type != "UInt32" {
+		t.Errorf("expected both attributes and typedefs but got %+v", iface)
+	}
+
+}
+
+func TestParseFidl_LenientBroadcasts(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`package test
+interface Device {
+	broadcast Changed {
+		in { String ignored }
+		out { String name }
+		error { Failed }
+	}
+	attribute UInt32 id readonly readonly
+}`))
+	parser.Mode = Lenient
+
+	//when
+	fidl, err := parser.Parse()
+
+	//then
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	if len(parser.Warnings) != 3 {
+		t.Errorf("expected 3 warnings but got %v", parser.Warnings)
+	}
+
+	iface := fidl.Interfaces[0]
+	if len(iface.Broadcasts) != 1 || len(iface.Broadcasts[0].Out) != 1 || iface.Broadcasts[0].Out[0].Name != "name" {
+		t.Errorf("expected broadcast with out parameter name but got %+v", iface.Broadcasts)
+	}
+
+	if len(iface.Attributes) != 1 || !iface.Attributes[0].ReadOnly {
+		t.Errorf("expected readonly attribute id but got %+v", iface.Attributes)
+	}
+
+}
+
+func TestParseFidl_MultipleErrors(t *testing.T) {
+
+	//given
+	parser := NewParser(strings.NewReader(`package test
+
+interface Device {
+	attribute UInt32
+	method Reset {
+		in {
+			UInt32
+		}
+	}
+	methd Start {
+	}
+	typedef Id UInt32
+	broadcast Changed {
+		out {
+			String name
+		}
+	}
+}
+
+attribute String stray
+}
+
+typeCollection Types {
+	map Options { String UInt32 }
+	enumeration Color { RED GREEN = x }
+}`))
+
+	//when
+	_, err := parser.Parse()
+
+	//then
+	var errs ErrorList
+	if !errors.As(err, &errs) {
+		t.Errorf("expected an ErrorList but got %v", err)
+		return
+	}
+
+	expected := []string{
+		`5:2: expected attribute name but got "method"`,
+		`8:3: expected argument name but got "}"`,
+		`10:2: expected attribute, method, broadcast or type definition in interface Device but got "methd"`,
+		`12:13: expected is but got "UInt32"`,
+		`20:1: expected interface or typeCollection but got "attribute"`,
+		`24:23: expected to but got "UInt32"`,
+		`25:34: expected value of enumerator GREEN but got "x"`,
+	}
+	if errs.Error() != strings.Join(expected, "\n") {
+		t.Errorf("wrong errors. expected\n%s\nbut got\n%s", strings.Join(expected, "\n"), errs.Error())
+	}
+
+}
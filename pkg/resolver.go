@@ -0,0 +1,280 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolver loads FIDL files together with the files they import. Imported
+// files are searched relative to the importing file first and then in the
+// include paths. Every file is parsed only once.
+type Resolver struct {
+	// Mode is the mode the files are parsed in.
+	Mode Mode
+	// Warnings holds the warnings of all files parsed in lenient mode.
+	Warnings []*ParseError
+
+	includePaths []string
+	cache        map[string]*Fidl
+	loading      []string
+}
+
+// NewResolver returns a new instance of Resolver.
+func NewResolver(includePaths ...string) *Resolver {
+	return &Resolver{
+		includePaths: includePaths,
+		cache:        map[string]*Fidl{},
+	}
+}
+
+// Load parses the FIDL file at path and all files it imports.
+func (r *Resolver) Load(path string) (*Fidl, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.load(absPath)
+}
+
+func (r *Resolver) load(path string) (*Fidl, error) {
+	for i, loading := range r.loading {
+		if loading == path {
+			var cycle []string
+			for _, file := range append(r.loading[i:], path) {
+				cycle = append(cycle, filepath.Base(file))
+			}
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	if fidl, ok := r.cache[path]; ok {
+		return fidl, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parser := NewFileParser(path, file)
+	parser.Mode = r.Mode
+	fidl, err := parser.Parse()
+	r.Warnings = append(r.Warnings, parser.Warnings...)
+	if err != nil {
+		// parse errors already carry the file name
+		var errs ErrorList
+		if errors.As(err, &errs) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	r.loading = append(r.loading, path)
+	defer func() { r.loading = r.loading[:len(r.loading)-1] }()
+
+	for i, imp := range fidl.PackageInfo.Imports {
+		importPath, err := r.find(imp.From, filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		imported, err := r.load(importPath)
+		if err != nil {
+			return nil, err
+		}
+
+		fidl.PackageInfo.Imports[i].Fidl = imported
+	}
+
+	r.cache[path] = fidl
+
+	return fidl, nil
+}
+
+// find returns the absolute path of an imported file.
+func (r *Resolver) find(importPath, dir string) (string, error) {
+	if filepath.IsAbs(importPath) {
+		return importPath, nil
+	}
+
+	for _, searchDir := range append([]string{dir}, r.includePaths...) {
+		candidate, err := filepath.Abs(filepath.Join(searchDir, importPath))
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("imported file %q not found", importPath)
+}
+
+// typeDefinition is a type declared in a FIDL file or one of its imports.
+type typeDefinition struct {
+	// Fidl is the file declaring the type.
+	Fidl *Fidl
+	// Types contains the declaration of the type.
+	Types Types
+	// Name is the unqualified name of the type.
+	Name string
+}
+
+// typeScope is a type collection or interface with its qualified name.
+type typeScope struct {
+	fidl      *Fidl
+	qualifier string
+	types     Types
+}
+
+// resolveType looks up the declaration of a type reference. References
+// may be qualified like org.example.Types.UnitInfo or Types.UnitInfo.
+// Unqualified references are searched in the file itself first and in
+// the imported files afterwards.
+func resolveType(fidl *Fidl, name string) (typeDefinition, bool) {
+	qualifier, typeName := "", name
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		qualifier, typeName = name[:idx], name[idx+1:]
+	}
+
+	for _, scope := range typeScopes(fidl) {
+		if qualifier != "" && scope.qualifier != qualifier && !strings.HasSuffix(scope.qualifier, "."+qualifier) {
+			continue
+		}
+
+		if scope.types.declares(typeName) {
+			return typeDefinition{Fidl: scope.fidl, Types: scope.types, Name: typeName}, true
+		}
+	}
+
+	return typeDefinition{}, false
+}
+
+// resolveInterface looks up the declaration of an interface reference like
+// org.example.Base or Base. The file declaring the interface is returned
+// as well.
+func resolveInterface(fidl *Fidl, name string) (*Fidl, Interface, bool) {
+	for _, file := range importedFiles(fidl, true) {
+		packageName := ""
+		if file.PackageInfo != nil {
+			packageName = file.PackageInfo.Name
+		}
+
+		for _, iface := range file.Interfaces {
+			qualifiedName := fmt.Sprintf("%s.%s", packageName, iface.Name)
+			if name == iface.Name || name == qualifiedName || strings.HasSuffix(qualifiedName, "."+name) {
+				return file, iface, true
+			}
+		}
+	}
+
+	return nil, Interface{}, false
+}
+
+// typeScopes returns the type collections and interfaces of fidl and all
+// files imported by it. The scopes of fidl come first.
+func typeScopes(fidl *Fidl) []typeScope {
+	var scopes []typeScope
+
+	for _, file := range importedFiles(fidl, true) {
+		packageName := ""
+		if file.PackageInfo != nil {
+			packageName = file.PackageInfo.Name
+		}
+
+		for _, typeCollection := range file.TypeCollections {
+			qualifier := packageName
+			if typeCollection.Name != "" {
+				qualifier = fmt.Sprintf("%s.%s", packageName, typeCollection.Name)
+			}
+			scopes = append(scopes, typeScope{file, qualifier, typeCollection.Types})
+		}
+
+		for _, iface := range file.Interfaces {
+			qualifier := fmt.Sprintf("%s.%s", packageName, iface.Name)
+			scopes = append(scopes, typeScope{file, qualifier, iface.Types})
+		}
+	}
+
+	return scopes
+}
+
+// importedFiles returns all files imported by fidl directly or indirectly.
+// Every file is returned once, fidl itself is added first if requested.
+func importedFiles(fidl *Fidl, includeSelf bool) []*Fidl {
+	seen := map[*Fidl]bool{fidl: true}
+	queue := []*Fidl{fidl}
+
+	var files []*Fidl
+	if includeSelf {
+		files = append(files, fidl)
+	}
+
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+
+		if file.PackageInfo == nil {
+			continue
+		}
+
+		for _, imp := range file.PackageInfo.Imports {
+			if imp.Fidl == nil || seen[imp.Fidl] {
+				continue
+			}
+
+			seen[imp.Fidl] = true
+			files = append(files, imp.Fidl)
+			queue = append(queue, imp.Fidl)
+		}
+	}
+
+	return files
+}
+
+// declares reports whether a type with the given name is declared.
+func (t Types) declares(name string) bool {
+	for _, str := range t.Structs {
+		if str.Name == name {
+			return true
+		}
+	}
+
+	for _, typeDef := range t.TypeDefs {
+		if typeDef.Name == name {
+			return true
+		}
+	}
+
+	for _, arrayDef := range t.ArrayDef {
+		if arrayDef.Name == name {
+			return true
+		}
+	}
+
+	for _, enum := range t.Enumerations {
+		if enum.Name == name {
+			return true
+		}
+	}
+
+	for _, mapDef := range t.Maps {
+		if mapDef.Name == name {
+			return true
+		}
+	}
+
+	for _, union := range t.Unions {
+		if union.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolver_Load(t *testing.T) {
+
+	//given
+	resolver := NewResolver()
+
+	//when
+	fidl, err := resolver.Load("../examples/Imports.fidl")
+
+	//then
+	if err != nil {
+		t.Errorf("could not load fidl because of: %v", err)
+		return
+	}
+
+	imported := fidl.PackageInfo.Imports[0].Fidl
+	if imported == nil || imported.PackageInfo.Name != "org.example.common" {
+		t.Errorf("import was not resolved: %+v", fidl.PackageInfo.Imports[0])
+		return
+	}
+
+	for _, name := range []string{"org.example.common.Types.Job", "Types.Job", "JobState"} {
+		definition, ok := resolveType(fidl, name)
+		if !ok || definition.Fidl != imported {
+			t.Errorf("type %s was not resolved to the imported file", name)
+		}
+	}
+
+	if _, ok := resolveType(fidl, "other.Types.Job"); ok {
+		t.Errorf("type with wrong qualifier must not be resolved")
+	}
+
+}
+
+func TestResolver_IncludePaths(t *testing.T) {
+
+	//given
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Service.fidl"), `package org.example
+import org.example.common.Types.* from "CommonTypes.fidl"
+interface Service {
+}`)
+	resolver := NewResolver("../examples")
+
+	//when
+	fidl, err := resolver.Load(filepath.Join(dir, "Service.fidl"))
+
+	//then
+	if err != nil {
+		t.Errorf("could not load fidl because of: %v", err)
+		return
+	}
+
+	if _, ok := resolveType(fidl, "Job"); !ok {
+		t.Errorf("type of file in include path was not resolved")
+	}
+
+}
+
+func TestResolver_ImportCycle(t *testing.T) {
+
+	//given
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.fidl"), `package a
+import b.* from "b.fidl"
+typeCollection {
+}`)
+	writeFile(t, filepath.Join(dir, "b.fidl"), `package b
+import a.* from "a.fidl"
+typeCollection {
+}`)
+	resolver := NewResolver()
+
+	//when
+	_, err := resolver.Load(filepath.Join(dir, "a.fidl"))
+
+	//then
+	if err == nil || !strings.Contains(err.Error(), "import cycle: a.fidl -> b.fidl -> a.fidl") {
+		t.Errorf("expected import cycle error but got: %v", err)
+	}
+
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,11 +5,65 @@ import _ "embed"
 //go:embed Sender-template.gotmpl
 var SenderTemplate string
 
+//go:embed Sender-common.gotmpl
+var SenderCommonTemplate string
+
 //go:embed Receiver-template.gotmpl
 var ReceiverTemplate string
 
+//go:embed Server-template.gotmpl
+var ServerTemplate string
+
+//go:embed Server-common.gotmpl
+var ServerCommonTemplate string
+
+//go:embed Receiver-common.gotmpl
+var ReceiverCommonTemplate string
+
+//go:embed Dispatcher.gotmpl
+var DispatcherTemplate string
+
 //go:embed Struct.gotmpl
 var StructTemplate string
 
 //go:embed DBusInterface.gotmpl
 var DBusInterfaceTemplate string
+
+//go:embed TypeDef.gotmpl
+var TypeDefTemplate string
+
+//go:embed ArrayDef.gotmpl
+var ArrayDefTemplate string
+
+//go:embed Enumeration.gotmpl
+var EnumerationTemplate string
+
+//go:embed Map.gotmpl
+var MapTemplate string
+
+//go:embed Union.gotmpl
+var UnionTemplate string
+
+//go:embed Decode.gotmpl
+var DecodeTemplate string
+
+//go:embed Constant.gotmpl
+var ConstantTemplate string
+
+//go:embed Errors.gotmpl
+var ErrorsTemplate string
+
+//go:embed Attributes.gotmpl
+var AttributesTemplate string
+
+//go:embed Events.gotmpl
+var EventsTemplate string
+
+//go:embed Signals.gotmpl
+var SignalsTemplate string
+
+//go:embed File.gotmpl
+var FileTemplate string
+
+//go:embed Types.gotmpl
+var TypesTemplate string
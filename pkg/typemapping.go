@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"bufio"
+	"fmt"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// TypeMapping binds a FIDL type to an existing Go type, which is used instead
+// of generating the type.
+type TypeMapping struct {
+	// GoImport is the import path of the package declaring the Go type. It
+	// is empty for types of the target package.
+	GoImport string
+	// GoImportName is the name the package is imported as if it was declared
+	// explicitly. Otherwise the name is derived from the import path.
+	GoImportName string
+	// GoType is the type as used in the generated code, e.g. notification.Hint.
+	GoType string
+	// Signature is the D-Bus signature the Go type is marshalled as.
+	Signature string
+}
+
+// ReadTypeMappings reads a type mapping file. Every line maps a FIDL type to a
+// Go type, optionally prefixed with its import path, and the D-Bus signature.
+// The import path can be preceded by the package name followed by =, which is
+// required if the name can't be derived from the path:
+//
+//	Hint     example.com/notification.Hint a{sv}
+//	Duration time.Duration                 x
+//	Node     yaml=gopkg.in/yaml.v3.Node    a{sv}
+//
+// Empty lines and lines starting with # are ignored.
+func ReadTypeMappings(r io.Reader) (map[string]TypeMapping, error) {
+	mappings := map[string]TypeMapping{}
+	// importNames holds the package name of every import path and importPaths
+	// the import path of every package name, so both are used consistently
+	importNames := map[string]string{}
+	importPaths := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected FIDL type, Go type and D-Bus signature but got %q", line, text)
+		}
+
+		if _, ok := mappings[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: type %s is already mapped", line, fields[0])
+		}
+
+		if err := validateSignature(fields[2]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		mapping := TypeMapping{GoType: fields[1], Signature: fields[2]}
+		if idx := strings.LastIndex(fields[1], "."); idx != -1 {
+			goType := fields[1][idx+1:]
+			mapping.GoImport = fields[1][:idx]
+
+			name := packageNameOf(mapping.GoImport)
+			if alias, path, found := strings.Cut(mapping.GoImport, "="); found {
+				if !token.IsIdentifier(alias) || alias == "_" {
+					return nil, fmt.Errorf("line %d: invalid package name %q", line, alias)
+				}
+				mapping.GoImport = path
+				mapping.GoImportName = alias
+				name = alias
+			} else if !token.IsIdentifier(name) {
+				return nil, fmt.Errorf("line %d: package name of %s can't be derived from the import path, declare it like name=%s", line, mapping.GoImport, fields[1])
+			}
+
+			if other, ok := importNames[mapping.GoImport]; ok && other != name {
+				return nil, fmt.Errorf("line %d: %s is already imported as %s", line, mapping.GoImport, other)
+			}
+			if other, ok := importPaths[name]; ok && other != mapping.GoImport {
+				return nil, fmt.Errorf("line %d: package name %s is already used for %s", line, name, other)
+			}
+			importNames[mapping.GoImport] = name
+			importPaths[name] = mapping.GoImport
+
+			mapping.GoType = fmt.Sprintf("%s.%s", name, goType)
+		}
+
+		mappings[fields[0]] = mapping
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return mappings, nil
+}
+
+// validateSignature checks that signature only consists of D-Bus type codes
+// with balanced brackets.
+func validateSignature(signature string) error {
+	var open []rune
+	for _, code := range signature {
+		switch code {
+		case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'h', 'v', 'a':
+		case '(', '{':
+			open = append(open, code)
+		case ')', '}':
+			if len(open) == 0 || (code == ')') != (open[len(open)-1] == '(') {
+				return fmt.Errorf("invalid D-Bus signature %q", signature)
+			}
+			open = open[:len(open)-1]
+		default:
+			return fmt.Errorf("invalid D-Bus signature %q", signature)
+		}
+	}
+
+	if len(open) != 0 || strings.HasSuffix(signature, "a") {
+		return fmt.Errorf("invalid D-Bus signature %q", signature)
+	}
+
+	return nil
+}
+
+// typeMapping returns the mapping of a FIDL type, which is either referenced
+// by the mapped name or resolves to a declaration with the mapped name.
+func typeMapping(fidl *Fidl, typeName string) (TypeMapping, bool) {
+	if mapping, ok := fidl.TypeMappings[typeName]; ok {
+		return mapping, true
+	}
+
+	if definition, ok := resolveType(fidl, typeName); ok {
+		mapping, ok := fidl.TypeMappings[definition.Name]
+		return mapping, ok
+	}
+
+	return TypeMapping{}, false
+}
+
+// unmappedTypes returns types without the declarations replaced by a mapping
+// and without typedefs named like a basic type, which always refers to the
+// basic type.
+func unmappedTypes(fidl *Fidl, types Types) Types {
+	mapped := func(name string) bool {
+		_, ok := fidl.TypeMappings[name]
+		return ok
+	}
+
+	result := Types{Constants: types.Constants}
+	for _, str := range types.Structs {
+		if !mapped(str.Name) {
+			result.Structs = append(result.Structs, str)
+		}
+	}
+	for _, typeDef := range types.TypeDefs {
+		if _, basic := basicTypes[typeDef.Name]; !mapped(typeDef.Name) && !basic {
+			result.TypeDefs = append(result.TypeDefs, typeDef)
+		}
+	}
+	for _, arrayDef := range types.ArrayDef {
+		if !mapped(arrayDef.Name) {
+			result.ArrayDef = append(result.ArrayDef, arrayDef)
+		}
+	}
+	for _, enum := range types.Enumerations {
+		if !mapped(enum.Name) {
+			result.Enumerations = append(result.Enumerations, enum)
+		}
+	}
+	for _, mapDef := range types.Maps {
+		if !mapped(mapDef.Name) {
+			result.Maps = append(result.Maps, mapDef)
+		}
+	}
+	for _, union := range types.Unions {
+		if !mapped(union.Name) {
+			result.Unions = append(result.Unions, union)
+		}
+	}
+
+	return result
+}
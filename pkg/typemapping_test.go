@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadTypeMappings(t *testing.T) {
+
+	//given
+	file := `# FIDL type  Go type  D-Bus signature
+Hint     example.com/notification.Hint a{sv}
+
+Duration time.Duration                 x
+Local    LocalType                     (si)
+Node     yaml=gopkg.in/yaml.v3.Node    a{sv}
+Unit     unit=example.com/go-systemd.Unit (ss)
+`
+
+	//when
+	mappings, err := ReadTypeMappings(strings.NewReader(file))
+
+	//then
+	if err != nil {
+		t.Errorf("could not read type mappings because of: %v", err)
+		return
+	}
+
+	expected := map[string]TypeMapping{
+		"Hint":     {GoImport: "example.com/notification", GoType: "notification.Hint", Signature: "a{sv}"},
+		"Duration": {GoImport: "time", GoType: "time.Duration", Signature: "x"},
+		"Local":    {GoType: "LocalType", Signature: "(si)"},
+		"Node":     {GoImport: "gopkg.in/yaml.v3", GoImportName: "yaml", GoType: "yaml.Node", Signature: "a{sv}"},
+		"Unit":     {GoImport: "example.com/go-systemd", GoImportName: "unit", GoType: "unit.Unit", Signature: "(ss)"},
+	}
+
+	if len(mappings) != len(expected) {
+		t.Errorf("wrong number of mappings. expected %d but got %d", len(expected), len(mappings))
+	}
+
+	for name, mapping := range expected {
+		if mappings[name] != mapping {
+			t.Errorf("got wrong mapping for %s. expected %+v but got %+v", name, mapping, mappings[name])
+		}
+	}
+
+}
+
+func TestReadTypeMappings_Errors(t *testing.T) {
+
+	//given
+	table := []string{
+		"Hint notification.Hint",
+		"Hint notification.Hint a{sv} extra",
+		"Hint notification.Hint a{sv\n",
+		"Hint notification.Hint a(s}",
+		"Hint notification.Hint z",
+		"Hint notification.Hint a",
+		"Hint notification.Hint s\nHint other.Hint s",
+		"Node gopkg.in/yaml.v3.Node s",
+		"Unit example.com/go-systemd.Unit s",
+		"Node 3yaml=gopkg.in/yaml.v3.Node s",
+		"Node _=gopkg.in/yaml.v3.Node s",
+		"Node yaml=gopkg.in/yaml.v3.Node s\nTag yamlv3=gopkg.in/yaml.v3.Tag s",
+		"Node yaml=gopkg.in/yaml.v3.Node s\nDoc yaml=example.com/yaml.Doc s",
+	}
+	for _, file := range table {
+
+		//when
+		_, err := ReadTypeMappings(strings.NewReader(file))
+
+		//then
+		if err == nil {
+			t.Errorf("expected error for %q", file)
+		}
+
+	}
+
+}
+
+func TestWrite_TypeMappings(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(strings.NewReader(`package org.example
+interface Clock {
+	typedef Duration is Int64
+	struct Alarm {
+		String name
+		Duration after
+	}
+	method SetAlarm {
+		in {
+			Alarm alarm
+			Hint hints
+		}
+	}
+}`)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+	fidl.TypeMappings = map[string]TypeMapping{
+		"Duration": {GoImport: "time", GoType: "time.Duration", Signature: "x"},
+		"Hint":     {GoImport: "example.com/notification", GoType: "notification.Hint", Signature: "a{sv}"},
+	}
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ServerWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"\"time\"":                     1,
+		"\"example.com/notification\"": 1,
+		"type Duration":                0,
+		"After time.Duration":          1,
+		"hints notification.Hint":      2,
+		"<arg name=\"hints\" type=\"a{sv}\" direction=\"in\"/>": 1,
+		"<arg name=\"alarm\" type=\"(sx)\" direction=\"in\"/>":  1,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_TypeMappingsImportName(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(strings.NewReader(`package org.example
+interface Config {
+	method Load {
+		in {
+			Node root
+			Unit service
+		}
+	}
+}`)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+	fidl.TypeMappings, err = ReadTypeMappings(strings.NewReader(`Node yaml=gopkg.in/yaml.v3.Node a{sv}
+Unit unit=example.com/go-systemd.Unit (ss)`))
+	if err != nil {
+		t.Errorf("could not read type mappings because of: %v", err)
+		return
+	}
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"yaml \"gopkg.in/yaml.v3\"":       1,
+		"unit \"example.com/go-systemd\"": 1,
+		"root yaml.Node":                  2,
+		"service unit.Unit":               2,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
@@ -0,0 +1,281 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SourceFellows/go-fidl-dbus-generator/pkg/lexer"
+)
+
+// basicType describes how a FIDL basic type is represented in Go and on
+// D-Bus.
+type basicType struct {
+	goType    string
+	signature string
+}
+
+// basicTypes are the types known without declaration. D-Bus has no signed
+// 8-bit and no 32-bit floating point type, so Int8 and Float are widened.
+// Integer is the Franca integer without range.
+var basicTypes = map[string]basicType{
+	"String":     {"string", "s"},
+	"Boolean":    {"bool", "b"},
+	"UInt8":      {"uint8", "y"},
+	"UInt16":     {"uint16", "q"},
+	"UInt32":     {"uint32", "u"},
+	"UInt64":     {"uint64", "t"},
+	"Int8":       {"int16", "n"},
+	"Int16":      {"int16", "n"},
+	"Int32":      {"int32", "i"},
+	"Int64":      {"int64", "x"},
+	"Integer":    {"int64", "x"},
+	"Float":      {"float64", "d"},
+	"Double":     {"float64", "d"},
+	"ByteBuffer": {"[]byte", "ay"},
+	"ObjectPath": {"dbus.ObjectPath", "o"},
+	"UnixFD":     {"dbus.UnixFD", "h"},
+	"Variant":    {"dbus.Variant", "v"},
+}
+
+// basicSignatures are the D-Bus types which can be used as dictionary keys.
+const basicSignatures = "ybnqiuxtdsogh"
+
+func mapFidlTypeToGoType(fidlString string) string {
+	if basic, ok := basicTypes[fidlString]; ok {
+		return basic.goType
+	}
+
+	return fidlString
+}
+
+// typeSignature is the D-Bus signature of a declared type.
+type typeSignature struct {
+	Name      string
+	Signature string
+}
+
+// typeSignatures returns the D-Bus signatures of all types declared in types.
+func typeSignatures(fidl *Fidl, types Types) ([]typeSignature, error) {
+	var names []string
+	for _, str := range types.Structs {
+		names = append(names, str.Name)
+	}
+	for _, typeDef := range types.TypeDefs {
+		names = append(names, typeDef.Name)
+	}
+	for _, arrayDef := range types.ArrayDef {
+		names = append(names, arrayDef.Name)
+	}
+	for _, mapDef := range types.Maps {
+		names = append(names, mapDef.Name)
+	}
+	for _, union := range types.Unions {
+		names = append(names, union.Name)
+	}
+	for _, enum := range types.Enumerations {
+		names = append(names, enum.Name)
+	}
+
+	signatures := make([]typeSignature, 0, len(names))
+	for _, name := range names {
+		signature, err := dbusSignature(fidl, name, false, nil)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, typeSignature{name, signature})
+	}
+
+	return signatures, nil
+}
+
+// checkTypes returns an error if a type declared in fidl or in one of the
+// imported files generated with it has no valid D-Bus signature.
+func checkTypes(fidl *Fidl) error {
+	all := importedTypes(fidl)
+	for _, typeCollection := range fidl.TypeCollections {
+		all = append(all, typeCollection.Types)
+	}
+	for _, iface := range fidl.Interfaces {
+		all = append(all, iface.Types)
+	}
+
+	for _, types := range all {
+		if err := checkShadowing(types); err != nil {
+			return err
+		}
+		if err := checkUnions(fidl, types); err != nil {
+			return err
+		}
+		if _, err := typeSignatures(fidl, types); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkShadowing returns an error if a struct, array, enumeration, map or
+// union is named like a basic type. The name always refers to the basic type,
+// so the declaration could never be used. Typedefs like
+// "typedef ObjectPath is String" are common in Franca files and are skipped
+// instead, see unmappedTypes.
+func checkShadowing(types Types) error {
+	shadowed := func(kind, name string, pos lexer.Position) error {
+		if _, ok := basicTypes[name]; !ok {
+			return nil
+		}
+		return &ParseError{Pos: pos, Msg: fmt.Sprintf("%s %s shadows the basic type %s", kind, name, name)}
+	}
+
+	for _, str := range types.Structs {
+		if err := shadowed("struct", str.Name, str.Pos); err != nil {
+			return err
+		}
+	}
+	for _, arrayDef := range types.ArrayDef {
+		if err := shadowed("array", arrayDef.Name, arrayDef.Pos); err != nil {
+			return err
+		}
+	}
+	for _, enum := range types.Enumerations {
+		if err := shadowed("enumeration", enum.Name, enum.Pos); err != nil {
+			return err
+		}
+	}
+	for _, mapDef := range types.Maps {
+		if err := shadowed("map", mapDef.Name, mapDef.Pos); err != nil {
+			return err
+		}
+	}
+	for _, union := range types.Unions {
+		if err := shadowed("union", union.Name, union.Pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkUnions returns an error if an alternative of a union is a union itself
+// or if two alternatives have the same D-Bus signature. Unions are decoded by
+// the signature of the variant, so such alternatives couldn't be told apart.
+// Arrays of unions are allowed as they are sent as array of variants.
+func checkUnions(fidl *Fidl, types Types) error {
+	for _, union := range types.Unions {
+		alternatives := map[string]string{}
+		for _, field := range union.Fields {
+			if !field.IsArray && isUnion(fidl, field.Type) {
+				return &ParseError{Pos: field.Pos, Msg: fmt.Sprintf("alternative %s of union %s can't be the union %s", field.Name, union.Name, field.Type)}
+			}
+
+			signature, err := dbusSignature(fidl, field.Type, field.IsArray, nil)
+			if err != nil {
+				return err
+			}
+
+			if other, ok := alternatives[signature]; ok {
+				return &ParseError{Pos: field.Pos, Msg: fmt.Sprintf("alternatives %s and %s of union %s have the same D-Bus signature %s", other, field.Name, union.Name, signature)}
+			}
+			alternatives[signature] = field.Name
+		}
+	}
+
+	return nil
+}
+
+// dbusSignature returns the D-Bus signature of a FIDL type. visited holds
+// the types currently being resolved to detect recursive definitions.
+func dbusSignature(fidl *Fidl, typeName string, isArray bool, visited []string) (string, error) {
+	if isArray {
+		signature, err := dbusSignature(fidl, typeName, false, visited)
+		return "a" + signature, err
+	}
+
+	if mapping, ok := typeMapping(fidl, typeName); ok {
+		return mapping.Signature, nil
+	}
+
+	if basic, ok := basicTypes[typeName]; ok {
+		return basic.signature, nil
+	}
+
+	definition, ok := resolveType(fidl, typeName)
+	if !ok {
+		return "", fmt.Errorf("no D-Bus signature known for type %s", typeName)
+	}
+
+	for _, name := range visited {
+		if name == definition.Name {
+			return "", fmt.Errorf("type %s is defined recursively", definition.Name)
+		}
+	}
+	visited = append(visited, definition.Name)
+
+	types := definition.Types
+	for _, str := range types.Structs {
+		if str.Name != definition.Name {
+			continue
+		}
+
+		var signature strings.Builder
+		signature.WriteString("(")
+		for _, field := range str.Fields {
+			fieldSignature, err := dbusSignature(fidl, field.Type, field.IsArray, visited)
+			if err != nil {
+				return "", err
+			}
+			signature.WriteString(fieldSignature)
+		}
+		signature.WriteString(")")
+
+		return signature.String(), nil
+	}
+
+	for _, typeDef := range types.TypeDefs {
+		if typeDef.Name == definition.Name {
+			return dbusSignature(fidl, typeDef.Type, false, visited)
+		}
+	}
+
+	for _, arrayDef := range types.ArrayDef {
+		if arrayDef.Name == definition.Name {
+			return dbusSignature(fidl, arrayDef.Type, true, visited)
+		}
+	}
+
+	for _, enum := range types.Enumerations {
+		if enum.Name == definition.Name {
+			backingType := fidl.EnumBackingType
+			if backingType == "" {
+				backingType = "Int32"
+			}
+			return dbusSignature(fidl, backingType, false, visited)
+		}
+	}
+
+	for _, mapDef := range types.Maps {
+		if mapDef.Name != definition.Name {
+			continue
+		}
+
+		keySignature, err := dbusSignature(fidl, mapDef.KeyType, false, visited)
+		if err != nil {
+			return "", err
+		}
+
+		// D-Bus only allows basic types as dictionary keys
+		if len(keySignature) != 1 || !strings.Contains(basicSignatures, keySignature) {
+			return "", &ParseError{Pos: mapDef.Pos, Msg: fmt.Sprintf("key type %s of map %s is not a D-Bus basic type", mapDef.KeyType, mapDef.Name)}
+		}
+
+		valueSignature, err := dbusSignature(fidl, mapDef.ValueType, false, visited)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("a{%s%s}", keySignature, valueSignature), nil
+	}
+
+	// unions are sent as variants
+	return "v", nil
+}
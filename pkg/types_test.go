@@ -0,0 +1,201 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapFidlTypeToGoType(t *testing.T) {
+
+	//given
+	table := []struct {
+		fidlType       string
+		expectedGoType string
+	}{
+		{"UInt64", "uint64"},
+		{"Int8", "int16"},
+		{"Float", "float64"},
+		{"Integer", "int64"},
+		{"ByteBuffer", "[]byte"},
+		{"ObjectPath", "dbus.ObjectPath"},
+		{"UnixFD", "dbus.UnixFD"},
+		{"Variant", "dbus.Variant"},
+		{"JobInfo", "JobInfo"},
+	}
+	for _, row := range table {
+
+		//when
+		result := mapFidlTypeToGoType(row.fidlType)
+
+		//then
+		if result != row.expectedGoType {
+			t.Errorf("got wrong Go type for %s. expected %v but got %v", row.fidlType, row.expectedGoType, result)
+		}
+
+	}
+
+}
+
+func TestDBusSignature(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(strings.NewReader(`package org.example
+typeCollection {
+	struct Point {
+		Int32 x
+		Int32 y
+	}
+	typedef Name is String
+	array Points of Point
+	map Names {
+		String to Point
+	}
+	enumeration Level {
+		LOW
+	}
+	union Value {
+		UInt32 number
+		String text
+	}
+	struct Loop {
+		Loop next
+	}
+	map ByName { Name to String }
+	map ByLevel { Level to String }
+	map ByPoint { Point to String }
+	map ByVariant { Variant to String }
+	map ByNames { Names to String }
+}`)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	table := []struct {
+		typeName          string
+		isArray           bool
+		expectedSignature string
+		expectError       bool
+	}{
+		{"UInt64", false, "t", false},
+		{"Int8", false, "n", false},
+		{"Float", false, "d", false},
+		{"Integer", false, "x", false},
+		{"ByteBuffer", true, "aay", false},
+		{"ObjectPath", true, "ao", false},
+		{"UnixFD", false, "h", false},
+		{"Variant", false, "v", false},
+		{"String", true, "as", false},
+		{"Point", false, "(ii)", false},
+		{"Name", false, "s", false},
+		{"Points", false, "a(ii)", false},
+		{"Names", false, "a{s(ii)}", false},
+		{"Level", true, "ai", false},
+		{"Value", false, "v", false},
+		{"Loop", false, "", true},
+		{"ByName", false, "a{ss}", false},
+		{"ByLevel", false, "a{is}", false},
+		{"ByPoint", false, "", true},
+		{"ByVariant", false, "", true},
+		{"ByNames", false, "", true},
+		{"Unknown", false, "", true},
+	}
+	for _, row := range table {
+
+		//when
+		result, err := dbusSignature(fidl, row.typeName, row.isArray, nil)
+
+		//then
+		if row.expectError && err == nil {
+			t.Errorf("expected error for type %s", row.typeName)
+		}
+
+		if !row.expectError && result != row.expectedSignature {
+			t.Errorf("got wrong signature for %s. expected %v but got %v (%v)", row.typeName, row.expectedSignature, result, err)
+		}
+
+	}
+
+	_, err = dbusSignature(fidl, "ByPoint", false, nil)
+	if expected := "24:2: key type Point of map ByPoint is not a D-Bus basic type"; err == nil || err.Error() != expected {
+		t.Errorf("wrong error for struct map key. expected %s but got %v", expected, err)
+	}
+
+}
+
+func TestCheckTypes_Shadowing(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		fidl     string
+		expected string
+	}{
+		{"typedef", "package test\ninterface Device {\n\ttypedef ObjectPath is String\n}", ""},
+		{"struct", "package test\ninterface Device {\n\tstruct String {\n\t\tUInt8[] bytes\n\t}\n}", "3:2: struct String shadows the basic type String"},
+		{"enumeration", "package test\ninterface Device {\n\tenumeration Boolean {\n\t\tYes\n\t\tNo\n\t}\n}", "3:2: enumeration Boolean shadows the basic type Boolean"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			//given
+			fidl, err := NewParser(strings.NewReader(test.fidl)).Parse()
+			if err != nil {
+				t.Errorf("could not parse fidl because of: %v", err)
+				return
+			}
+
+			//when
+			err = checkTypes(fidl)
+
+			//then
+			if test.expected == "" && err != nil {
+				t.Errorf("expected no error but got %v", err)
+			}
+
+			if test.expected != "" && (err == nil || err.Error() != test.expected) {
+				t.Errorf("wrong error. expected %s but got %v", test.expected, err)
+			}
+		})
+	}
+
+}
+
+func TestCheckTypes_Unions(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		fidl     string
+		expected string
+	}{
+		{"distinct", "package test\ninterface Device {\n\tunion Value {\n\t\tString text\n\t\tUInt32 number\n\t\tValue[] values\n\t}\n}", ""},
+		{"same type", "package test\ninterface Device {\n\tunion Value {\n\t\tString a\n\t\tString b\n\t}\n}", "5:3: alternatives a and b of union Value have the same D-Bus signature s"},
+		{"enumeration", "package test\ninterface Device {\n\tenumeration Level {\n\t\tLOW\n\t}\n\tunion Value {\n\t\tInt32 number\n\t\tLevel level\n\t}\n}", "8:3: alternatives number and level of union Value have the same D-Bus signature i"},
+		{"nested union", "package test\ninterface Device {\n\tunion Inner {\n\t\tString text\n\t}\n\tunion Outer {\n\t\tUInt32 number\n\t\tInner inner\n\t}\n}", "8:3: alternative inner of union Outer can't be the union Inner"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			//given
+			fidl, err := NewParser(strings.NewReader(test.fidl)).Parse()
+			if err != nil {
+				t.Errorf("could not parse fidl because of: %v", err)
+				return
+			}
+
+			//when
+			err = checkTypes(fidl)
+
+			//then
+			if test.expected == "" && err != nil {
+				t.Errorf("expected no error but got %v", err)
+			}
+
+			if test.expected != "" && (err == nil || err.Error() != test.expected) {
+				t.Errorf("wrong error. expected %s but got %v", test.expected, err)
+			}
+		})
+	}
+
+}
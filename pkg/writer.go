@@ -7,6 +7,7 @@ import (
 	"github.com/SourceFellows/go-fidl-dbus-generator/pkg/templates"
 	"go/format"
 	"io"
+	"strconv"
 	"strings"
 	"text/template"
 	"unicode"
@@ -14,34 +15,160 @@ import (
 
 type WriterType struct {
 	template string
+	// common is generated once per file in addition to the interfaces.
+	common string
 }
 
 var (
-	ReceiverWriter = WriterType{templates.ReceiverTemplate}
-	SenderWriter   = WriterType{templates.SenderTemplate}
+	ReceiverWriter = WriterType{templates.ReceiverTemplate, templates.ReceiverCommonTemplate}
+	SenderWriter   = WriterType{templates.SenderTemplate, templates.SenderCommonTemplate}
+	ServerWriter   = WriterType{templates.ServerTemplate, templates.ServerCommonTemplate}
 )
 
+// interfaceData is the data the writer templates are executed with for
+// every interface of a FIDL file. Methods, Attributes and Broadcasts include
+// the members inherited from base interfaces.
+type interfaceData struct {
+	Interface
+	PackageInfo *PackageInfo
+	Methods     []interfaceMethod
+	Attributes  []interfaceAttribute
+	Broadcasts  []interfaceBroadcast
+	// DBusInterfaces are the names of the interface and all its base
+	// interfaces, base interfaces first.
+	DBusInterfaces []string
+}
+
+// interfaceMethod is a method together with the fully qualified name of
+// the D-Bus interface declaring it.
+type interfaceMethod struct {
+	Method
+	DBusInterface string
+}
+
+// interfaceAttribute is an attribute together with the fully qualified
+// name of the D-Bus interface declaring it and the names of its generated
+// accessors.
+type interfaceAttribute struct {
+	Attribute
+	DBusInterface string
+	Getter        string
+	Setter        string
+	Watcher       string
+}
+
+// interfaceBroadcast is a broadcast together with the fully qualified name
+// of the D-Bus interface declaring it.
+type interfaceBroadcast struct {
+	Broadcast
+	DBusInterface string
+}
+
 func Write(fidl *Fidl, writerType WriterType, writer io.Writer) error {
 
+	enumType, err := enumBackingType(fidl)
+	if err != nil {
+		return err
+	}
+
+	if err := checkGoPackages(fidl); err != nil {
+		return err
+	}
+
+	if err := checkTypes(fidl); err != nil {
+		return err
+	}
+
 	funcs := template.FuncMap{
 		"nameify":               toGoIdentifierName,
 		"extractLastPartOfName": extractLastPartOfName,
 		"exportNameOf":          exportNameOf,
-		"goType":                mapFidlTypeToGoType,
-		"derefStr":              deref,
+		"goType": func(fidlType string) string {
+			return goTypeName(fidl, fidlType)
+		},
+		"derefStr": deref,
+		"comment":  toGoComment,
+		"enumType": func() string { return enumType },
+		"enumerators": func(enum Enumeration) ([]enumeratorValue, error) {
+			enumerators, err := resolveEnumerators(fidl, enum, nil)
+			if err != nil {
+				return nil, err
+			}
+			return enumerators, checkEnumeratorRange(enum, enumerators, enumType)
+		},
+		"constValue": constantValue,
+		"asVariant": func(typeName string) bool {
+			return isUnion(fidl, typeName)
+		},
+		"decodable": func(typeName string) bool {
+			return needsDecoding(fidl, typeName, nil)
+		},
+		"encodeFunc": func(typeName string, isArray bool) string {
+			return unionFuncName(fidl, "Encode", typeName, isArray)
+		},
+		"decodeFunc": func(typeName string, isArray bool) string {
+			return unionFuncName(fidl, "Decode", typeName, isArray)
+		},
+		"unmapped": func(types Types) Types {
+			return unmappedTypes(fidl, types)
+		},
+		"typeSignatures": func(types Types) ([]typeSignature, error) {
+			return typeSignatures(fidl, types)
+		},
+		"interfaceData": func(iface Interface) (interfaceData, error) {
+			return newInterfaceData(fidl, iface)
+		},
+		"importedTypes": func() []Types {
+			return importedTypes(fidl)
+		},
+		"goImports": func() []goImport {
+			return goImports(fidl)
+		},
+		"methodErrors": func() ([]methodError, error) {
+			return methodErrors(fidl)
+		},
+		"signature": func(typeName string, isArray bool) (string, error) {
+			return dbusSignature(fidl, typeName, isArray, nil)
+		},
+		"unescape": stripEscape,
 	}
 
-	tmpl, err := template.New("type").
+	tmpl, err := template.New("File").
 		Funcs(funcs).
-		Parse(writerType.template)
-
-	tmpl.New("DBusInterface").Parse(templates.DBusInterfaceTemplate)
-	tmpl.New("Struct").Parse(templates.StructTemplate)
-
+		Parse(templates.FileTemplate)
 	if err != nil {
 		return err
 	}
 
+	subTemplates := []struct {
+		name     string
+		template string
+	}{
+		{"Interface", writerType.template},
+		{"Common", writerType.common},
+		{"DBusInterface", templates.DBusInterfaceTemplate},
+		{"Types", templates.TypesTemplate},
+		{"Struct", templates.StructTemplate},
+		{"TypeDef", templates.TypeDefTemplate},
+		{"ArrayDef", templates.ArrayDefTemplate},
+		{"Enumeration", templates.EnumerationTemplate},
+		{"Map", templates.MapTemplate},
+		{"Union", templates.UnionTemplate},
+		{"Decode", templates.DecodeTemplate},
+		{"Constant", templates.ConstantTemplate},
+		{"Errors", templates.ErrorsTemplate},
+		{"Attributes", templates.AttributesTemplate},
+		{"Events", templates.EventsTemplate},
+		{"Signals", templates.SignalsTemplate},
+		{"Dispatcher", templates.DispatcherTemplate},
+	}
+
+	for _, subTemplate := range subTemplates {
+		if _, err := tmpl.New(subTemplate.name).Parse(subTemplate.template); err != nil {
+			return err
+		}
+	}
+
 	var bites bytes.Buffer
 
 	err = tmpl.Execute(&bites, fidl)
@@ -49,7 +176,12 @@ func Write(fidl *Fidl, writerType WriterType, writer io.Writer) error {
 		return err
 	}
 
-	src, err := format.Source(bites.Bytes())
+	src, err := removeUnusedImports(bites.Bytes())
+	if err != nil {
+		return err
+	}
+
+	src, err = format.Source(src)
 	if err != nil {
 		return err
 	}
@@ -58,9 +190,211 @@ func Write(fidl *Fidl, writerType WriterType, writer io.Writer) error {
 	return err
 }
 
+// newInterfaceData collects the members of iface and of all interfaces it
+// extends. Inherited members come first.
+func newInterfaceData(fidl *Fidl, iface Interface) (interfaceData, error) {
+	data := interfaceData{Interface: iface, PackageInfo: fidl.PackageInfo}
+
+	chain := []Interface{iface}
+	files := []*Fidl{fidl}
+	for current := iface; current.Extends != ""; {
+		file, base, ok := resolveInterface(files[0], current.Extends)
+		if !ok {
+			return data, fmt.Errorf("interface %s extends unknown interface %s", current.Name, current.Extends)
+		}
+
+		for i, known := range chain {
+			if known.Name == base.Name && files[i] == file {
+				return data, fmt.Errorf("interface %s extends itself", base.Name)
+			}
+		}
+
+		chain = append([]Interface{base}, chain...)
+		files = append([]*Fidl{file}, files...)
+		current = base
+	}
+
+	declared := map[string]string{}
+	declare := func(kind, name, dbusInterface string) error {
+		key := fmt.Sprintf("%s %s", kind, name)
+		if previous, ok := declared[key]; ok {
+			return fmt.Errorf("%s %s of interface %s is already declared by %s", kind, name, dbusInterface, previous)
+		}
+		declared[key] = dbusInterface
+		return nil
+	}
+
+	for i, current := range chain {
+		packageName := ""
+		if files[i].PackageInfo != nil {
+			packageName = files[i].PackageInfo.Name
+		}
+		dbusInterface := fmt.Sprintf("%s.%s", packageName, current.Name)
+		data.DBusInterfaces = append(data.DBusInterfaces, dbusInterface)
+
+		for _, method := range current.Methods {
+			if err := declare("method", method.Name, dbusInterface); err != nil {
+				return data, err
+			}
+			data.Methods = append(data.Methods, interfaceMethod{method, dbusInterface})
+		}
+
+		for _, attribute := range current.Attributes {
+			if err := declare("attribute", attribute.Name, dbusInterface); err != nil {
+				return data, err
+			}
+			data.Attributes = append(data.Attributes, interfaceAttribute{Attribute: attribute, DBusInterface: dbusInterface})
+		}
+
+		for _, broadcast := range current.Broadcasts {
+			if err := declare("broadcast", broadcast.Name, dbusInterface); err != nil {
+				return data, err
+			}
+			data.Broadcasts = append(data.Broadcasts, interfaceBroadcast{broadcast, dbusInterface})
+		}
+	}
+
+	if err := nameAccessors(&data); err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// nameAccessors names the generated accessors of all attributes. They are
+// named Get<Attribute>, Set<Attribute> and Watch<Attribute> unless one of
+// these names is already taken by a method, a broadcast or the generated
+// Close and GetAttributes. Then all accessors of the attribute get the
+// suffix Attribute, e.g. SetEnvironmentAttribute.
+func nameAccessors(data *interfaceData) error {
+	taken := map[string]string{
+		"Close":         "the generated method Close",
+		"GetAttributes": "the generated method GetAttributes",
+	}
+	take := func(goName, owner string) error {
+		if previous, ok := taken[goName]; ok {
+			return fmt.Errorf("%s collides with %s, both are generated as %s", owner, previous, goName)
+		}
+		taken[goName] = owner
+		return nil
+	}
+
+	for _, method := range data.Methods {
+		owner := fmt.Sprintf("method %s of interface %s", method.Name, method.DBusInterface)
+		if err := take(exportNameOf(method.Name), owner); err != nil {
+			return err
+		}
+	}
+
+	for _, broadcast := range data.Broadcasts {
+		owner := fmt.Sprintf("broadcast %s of interface %s", broadcast.Name, broadcast.DBusInterface)
+		name := exportNameOf(broadcast.Name)
+		if err := take(fmt.Sprintf("Send%sSignal", name), owner); err != nil {
+			return err
+		}
+		if err := take(fmt.Sprintf("ListenFor%s", name), owner); err != nil {
+			return err
+		}
+	}
+
+	for i := range data.Attributes {
+		attribute := &data.Attributes[i]
+		name := exportNameOf(attribute.Name)
+
+		suffix := ""
+		for _, prefix := range []string{"Get", "Set", "Watch"} {
+			if _, ok := taken[prefix+name]; ok {
+				suffix = "Attribute"
+			}
+		}
+
+		attribute.Getter = "Get" + name + suffix
+		attribute.Setter = "Set" + name + suffix
+		attribute.Watcher = "Watch" + name + suffix
+
+		owner := fmt.Sprintf("attribute %s of interface %s", attribute.Name, attribute.DBusInterface)
+		for _, accessor := range []string{attribute.Getter, attribute.Setter, attribute.Watcher} {
+			if err := take(accessor, owner); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// methodError is an error declared by a method which is transferred as
+// D-Bus error with the name DBusName.
+type methodError struct {
+	Description string
+	GoName      string
+	DBusName    string
+}
+
+// methodErrors returns the errors declared by the methods of all interfaces
+// including the inherited ones. Every D-Bus error name is returned once.
+func methodErrors(fidl *Fidl) ([]methodError, error) {
+	var result []methodError
+	seen := map[string]bool{}
+
+	for _, iface := range fidl.Interfaces {
+		data, err := newInterfaceData(fidl, iface)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, method := range data.Methods {
+			if method.Error == nil {
+				continue
+			}
+
+			enumerators, err := resolveMethodErrors(fidl, method.Method)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, enumerator := range enumerators {
+				name := stripEscape(enumerator.Name)
+				dbusName := fmt.Sprintf("%s.Error.%s", method.DBusInterface, name)
+				if seen[dbusName] {
+					continue
+				}
+				seen[dbusName] = true
+
+				result = append(result, methodError{
+					Description: enumerator.Description,
+					GoName:      fmt.Sprintf("Err%s%s", exportNameOf(extractLastPartOfName(method.DBusInterface)), exportNameOf(name)),
+					DBusName:    dbusName,
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// resolveMethodErrors returns the error enumerators of a method. Errors
+// may reference an enumeration or be declared inline.
+func resolveMethodErrors(fidl *Fidl, method Method) ([]enumeratorValue, error) {
+	if method.Error.Type != "" {
+		enum, ok := findEnumeration(fidl, method.Error.Type)
+		if !ok {
+			return nil, fmt.Errorf("method %s references unknown error enumeration %s", method.Name, method.Error.Type)
+		}
+
+		return resolveEnumerators(fidl, enum, nil)
+	}
+
+	return resolveEnumerators(fidl, Enumeration{
+		Name:        fmt.Sprintf("%sError", method.Name),
+		Extends:     method.Error.Extends,
+		Enumerators: method.Error.Enumerators,
+	}, nil)
+}
+
 func toGoIdentifierName(typeName string) string {
 
-	internalName := []rune(typeName)
+	internalName := []rune(stripEscape(typeName))
 	makeLower := true
 	for i, r := range internalName {
 		if !unicode.IsLower(r) && makeLower {
@@ -74,27 +408,384 @@ func toGoIdentifierName(typeName string) string {
 }
 
 func exportNameOf(name string) string {
+	name = stripEscape(name)
 	first := strings.ToUpper(string(name[0]))
 	return fmt.Sprintf("%s%s", first, name[1:])
 }
 
-func mapFidlTypeToGoType(fidlString string) string {
+// stripEscape removes the FIDL keyword escape '^' from a name.
+func stripEscape(name string) string {
+	return strings.TrimPrefix(name, "^")
+}
+
+// toGoComment renders a FIDL description as Go line comment.
+func toGoComment(description string) string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return ""
+	}
+
+	description = strings.TrimPrefix(description, "@description")
+	description = strings.TrimLeft(description, " \t:")
+
+	lines := strings.Split(description, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			lines[i] = "//"
+		} else {
+			lines[i] = fmt.Sprintf("// %s", line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// enumeratorValue is an enumerator with its resolved integer value.
+type enumeratorValue struct {
+	Description string
+	Name        string
+	Value       int64
+	// IsAlias is set if a previous enumerator already has the same value.
+	IsAlias bool
+}
+
+// enumBackingType returns the Go integer type enumerations are generated as.
+// It matches the D-Bus signature of the backing type, so Int8 is widened to
+// int16 like everywhere else.
+func enumBackingType(fidl *Fidl) (string, error) {
+	fidlType := fidl.EnumBackingType
+	if fidlType == "" {
+		fidlType = "Int32"
+	}
+
+	switch fidlType {
+	case "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32", "UInt64":
+		return basicTypes[fidlType].goType, nil
+	}
+
+	return "", fmt.Errorf("enumerations can't be backed by type %q", fidlType)
+}
+
+// resolveEnumerators returns the enumerators of enum including the ones
+// inherited by "extends". Implicit values continue from the previous
+// enumerator starting with 0.
+func resolveEnumerators(fidl *Fidl, enum Enumeration, visited []string) ([]enumeratorValue, error) {
+	for _, name := range visited {
+		if name == enum.Name {
+			return nil, fmt.Errorf("enumeration %s extends itself", enum.Name)
+		}
+	}
+	visited = append(visited, enum.Name)
+
+	var result []enumeratorValue
+	if enum.Extends != "" {
+		base, ok := findEnumeration(fidl, enum.Extends)
+		if !ok {
+			return nil, fmt.Errorf("enumeration %s extends unknown enumeration %s", enum.Name, enum.Extends)
+		}
+
+		baseEnumerators, err := resolveEnumerators(fidl, base, visited)
+		if err != nil {
+			return nil, err
+		}
+		result = baseEnumerators
+	}
+
+	next := int64(0)
+	if len(result) > 0 {
+		next = result[len(result)-1].Value + 1
+	}
+
+	for _, enumerator := range enum.Enumerators {
+		value := next
+		if enumerator.Value != "" {
+			parsed, err := strconv.ParseInt(enumerator.Value, 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q of enumerator %s.%s", enumerator.Value, enum.Name, enumerator.Name)
+			}
+			value = parsed
+		}
+
+		result = append(result, enumeratorValue{
+			Description: enumerator.Description,
+			Name:        enumerator.Name,
+			Value:       value,
+		})
+		next = value + 1
+	}
+
+	seen := map[int64]bool{}
+	for i := range result {
+		result[i].IsAlias = seen[result[i].Value]
+		seen[result[i].Value] = true
+	}
+
+	return result, nil
+}
+
+// checkEnumeratorRange returns an error if the value of an enumerator doesn't
+// fit into the Go integer type enumerations are backed by.
+func checkEnumeratorRange(enum Enumeration, enumerators []enumeratorValue, goType string) error {
+	for _, enumerator := range enumerators {
+		value := strconv.FormatInt(enumerator.Value, 10)
 
-	mappings := map[string]string{}
-	mappings["String"] = "string"
-	mappings["Boolean"] = "bool"
-	mappings["UInt8"] = "uint8"
-	mappings["UInt16"] = "uint16"
-	mappings["UInt32"] = "uint32"
-	mappings["Int8"] = "int8"
-	mappings["Int16"] = "int16"
-	mappings["Int32"] = "int32"
-	mappings["Int64"] = "int64"
+		var err error
+		if strings.HasPrefix(goType, "uint") {
+			bitSize, _ := strconv.Atoi(strings.TrimPrefix(goType, "uint"))
+			_, err = strconv.ParseUint(value, 10, bitSize)
+		} else {
+			bitSize, _ := strconv.Atoi(strings.TrimPrefix(goType, "int"))
+			_, err = strconv.ParseInt(value, 10, bitSize)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid value %s for enumerator %s.%s of type %s", value, enum.Name, enumerator.Name, goType)
+		}
+	}
+
+	return nil
+}
+
+func findEnumeration(fidl *Fidl, name string) (Enumeration, bool) {
+	definition, ok := resolveType(fidl, name)
+	if !ok {
+		return Enumeration{}, false
+	}
+
+	for _, enum := range definition.Types.Enumerations {
+		if enum.Name == definition.Name {
+			return enum, true
+		}
+	}
+
+	return Enumeration{}, false
+}
 
-	if v, ok := mappings[fidlString]; ok {
-		return v
+// goTypeName returns the Go type of a FIDL type reference. Types declared
+// in an imported FIDL package which is mapped to a Go package are
+// qualified with the name of the Go package.
+func goTypeName(fidl *Fidl, fidlType string) string {
+	if mapping, ok := typeMapping(fidl, fidlType); ok {
+		return mapping.GoType
+	}
+
+	if goType := mapFidlTypeToGoType(fidlType); goType != fidlType {
+		return goType
+	}
+
+	definition, ok := resolveType(fidl, fidlType)
+	if !ok {
+		return fidlType
+	}
+
+	return qualifiedName(fidl, definition, exportNameOf(definition.Name))
+}
+
+// qualifiedName prefixes name with the Go package of definition if it is
+// declared in a mapped FIDL package.
+func qualifiedName(fidl *Fidl, definition typeDefinition, name string) string {
+	if goPackage, ok := goPackageOf(fidl, definition.Fidl); ok {
+		return fmt.Sprintf("%s.%s", goPackageName(goPackage), name)
+	}
+
+	return name
+}
+
+// goPackageOf returns the Go package of an imported FIDL file, see
+// splitGoPackage.
+func goPackageOf(fidl *Fidl, imported *Fidl) (string, bool) {
+	if imported == fidl || imported.PackageInfo == nil {
+		return "", false
+	}
+
+	goPackage, ok := fidl.GoPackages[imported.PackageInfo.Name]
+	return goPackage, ok
+}
+
+// importedTypes returns the type definitions of all imported files which
+// aren't mapped to a Go package. They are generated alongside the types of
+// the FIDL file itself.
+func importedTypes(fidl *Fidl) []Types {
+	var result []Types
+	for _, imported := range importedFiles(fidl, false) {
+		if _, ok := goPackageOf(fidl, imported); ok {
+			continue
+		}
+
+		for _, typeCollection := range imported.TypeCollections {
+			result = append(result, typeCollection.Types)
+		}
+
+		for _, iface := range imported.Interfaces {
+			result = append(result, iface.Types)
+		}
+	}
+
+	return result
+}
+
+// goImport is an import of the generated file. Name is empty if the package
+// is referenced by its own name.
+type goImport struct {
+	Name string
+	Path string
+}
+
+// goImports returns the Go imports of all imported FIDL packages and mapped
+// types.
+func goImports(fidl *Fidl) []goImport {
+	var result []goImport
+	add := func(imp goImport) {
+		for _, added := range result {
+			if added == imp {
+				return
+			}
+		}
+		result = append(result, imp)
 	}
-	return fidlString
+
+	for _, imported := range importedFiles(fidl, false) {
+		if goPackage, ok := goPackageOf(fidl, imported); ok {
+			name, path := splitGoPackage(goPackage)
+			add(goImport{Name: name, Path: path})
+		}
+	}
+
+	for _, mapping := range fidl.TypeMappings {
+		if mapping.GoImport != "" {
+			add(goImport{Name: mapping.GoImportName, Path: mapping.GoImport})
+		}
+	}
+
+	return result
+}
+
+// unionFuncName returns the name of the generated function which encodes
+// or decodes the given union type.
+func unionFuncName(fidl *Fidl, prefix, typeName string, isArray bool) string {
+	definition, _ := resolveType(fidl, typeName)
+
+	name := fmt.Sprintf("%s%s", prefix, exportNameOf(definition.Name))
+	if isArray {
+		name = fmt.Sprintf("%sSlice", name)
+	}
+
+	return qualifiedName(fidl, definition, name)
+}
+
+// constantValue returns the value of a constant as Go literal. The literal
+// is checked against the declared type of the constant.
+func constantValue(constant Constant) (string, error) {
+	value := constant.Value
+	invalid := fmt.Errorf("invalid value %s for constant %s of type %s", value, constant.Name, constant.Type)
+
+	switch constant.Type {
+	case "String":
+		if _, err := strconv.Unquote(value); err != nil {
+			return "", invalid
+		}
+	case "Boolean":
+		if value != "true" && value != "false" {
+			return "", invalid
+		}
+	case "Float", "Double":
+		value = strings.TrimRight(value, "fFdD")
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", invalid
+		}
+	case "Int8", "Int16", "Int32", "Int64":
+		bitSize, _ := strconv.Atoi(strings.TrimPrefix(constant.Type, "Int"))
+		if _, err := strconv.ParseInt(value, 0, bitSize); err != nil {
+			return "", invalid
+		}
+	case "UInt8", "UInt16", "UInt32", "UInt64":
+		bitSize, _ := strconv.Atoi(strings.TrimPrefix(constant.Type, "UInt"))
+		if _, err := strconv.ParseUint(value, 0, bitSize); err != nil {
+			return "", invalid
+		}
+	}
+
+	return value, nil
+}
+
+// isUnion reports whether typeName refers to a union of the FIDL file.
+// Unions are sent as D-Bus variants and need explicit encoding.
+func isUnion(fidl *Fidl, typeName string) bool {
+	if _, ok := typeMapping(fidl, typeName); ok {
+		return false
+	}
+
+	definition, ok := resolveType(fidl, typeName)
+	if !ok {
+		return false
+	}
+
+	for _, union := range definition.Types.Unions {
+		if union.Name == definition.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// needsDecoding reports whether typeName is a union or a type containing a
+// union. dbus.Store can't decode these, they are decoded from the raw message
+// values by generated Decode functions instead.
+func needsDecoding(fidl *Fidl, typeName string, visited []string) bool {
+	if isUnion(fidl, typeName) {
+		return true
+	}
+
+	if _, ok := typeMapping(fidl, typeName); ok {
+		return false
+	}
+
+	definition, ok := resolveType(fidl, typeName)
+	if !ok {
+		return false
+	}
+
+	for _, name := range visited {
+		if name == definition.Name {
+			return false
+		}
+	}
+	visited = append(visited, definition.Name)
+
+	types := definition.Types
+	for _, str := range types.Structs {
+		if str.Name != definition.Name {
+			continue
+		}
+
+		for _, field := range str.Fields {
+			if needsDecoding(fidl, field.Type, visited) {
+				return true
+			}
+		}
+	}
+
+	for _, typeDef := range types.TypeDefs {
+		if typeDef.Name == definition.Name {
+			return needsDecoding(fidl, typeDef.Type, visited)
+		}
+	}
+
+	for _, arrayDef := range types.ArrayDef {
+		if arrayDef.Name == definition.Name {
+			return needsDecoding(fidl, arrayDef.Type, visited)
+		}
+	}
+
+	for _, mapDef := range types.Maps {
+		if mapDef.Name == definition.Name {
+			return needsDecoding(fidl, mapDef.ValueType, visited)
+		}
+	}
+
+	return false
 }
 
 func deref(val *string) any {
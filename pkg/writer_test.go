@@ -1,6 +1,16 @@
 package pkg
 
-import "testing"
+import (
+	"bytes"
+	"fmt"
+	"github.com/SourceFellows/go-fidl-dbus-generator/examples"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
 
 func TestToGoIdentierName(t *testing.T) {
 
@@ -25,3 +35,1550 @@ func TestToGoIdentierName(t *testing.T) {
 	}
 
 }
+
+func TestExportNameOf(t *testing.T) {
+
+	//given
+	table := []struct {
+		name         string
+		expectedName string
+	}{
+		{"name", "Name"},
+		{"loadState", "LoadState"},
+		{"^state", "State"},
+	}
+	for _, row := range table {
+
+		result := exportNameOf(row.name)
+
+		if result != row.expectedName {
+			t.Errorf("got wrong value. expected %v but got %v", row.expectedName, result)
+		}
+
+	}
+
+}
+
+func TestWrite_Structs(t *testing.T) {
+
+	//given
+	fidl := &Fidl{
+		TargetPackage: "test",
+		PackageInfo:   &PackageInfo{Name: "org.example"},
+		Interfaces: []Interface{
+			{
+				InterfaceInfo: InterfaceInfo{Name: "Test"},
+				Types: Types{
+					Structs: []Struct{
+						{
+							Description: "@description : Job info structure",
+							Name:        "JobInfo",
+							Fields: []Param{
+								{Type: "UInt32", Name: "jobId"},
+								{Type: "String", Name: "^state", Description: "state of the job"},
+								{Type: "String", Name: "units", IsArray: true},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, writerType := range []WriterType{SenderWriter, ReceiverWriter} {
+
+		//when
+		var out bytes.Buffer
+		err := Write(fidl, writerType, &out)
+
+		//then
+		if err != nil {
+			t.Errorf("could not write fidl because of: %v", err)
+			return
+		}
+
+		expected := "// Job info structure\n" +
+			"type JobInfo struct {\n" +
+			"\tJobId uint32\n" +
+			"\t// state of the job\n" +
+			"\tState string\n" +
+			"\tUnits []string\n" +
+			"}\n"
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected struct definition\n%s\nin\n%s", expected, out.String())
+		}
+	}
+
+}
+
+func TestWrite_TypeDefsAndArrays(t *testing.T) {
+
+	//given
+	fidl := &Fidl{
+		TargetPackage: "test",
+		PackageInfo:   &PackageInfo{Name: "org.example"},
+		TypeCollections: []TypeCollection{
+			{
+				Types: Types{
+					TypeDefs: []TypeDef{
+						{Name: "UnitName", Type: "String"},
+						{Name: "ObjectPath", Type: "String"},
+					},
+					ArrayDef: []ArrayDef{
+						{Description: "A list of file names.", Name: "FileList", Type: "String"},
+					},
+				},
+			},
+		},
+	}
+
+	//when
+	var out bytes.Buffer
+	err := Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for _, expected := range []string{
+		"type UnitName = string\n",
+		"// A list of file names.\ntype FileList []string\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, out.String())
+		}
+	}
+
+	// the typedef shadows the basic type ObjectPath which is used instead
+	if strings.Contains(out.String(), "type ObjectPath") || strings.Contains(out.String(), "ObjectPathSignature") {
+		t.Errorf("unexpected declaration of ObjectPath in\n%s", out.String())
+	}
+
+}
+
+func TestResolveEnumerators(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.EnumerationsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+
+	//when
+	enumerators, err := resolveEnumerators(fidl, fidl.Interfaces[0].Enumerations[1], nil)
+
+	//then
+	if err != nil {
+		t.Errorf("could not resolve enumerators because of: %v", err)
+		return
+	}
+
+	expected := []enumeratorValue{
+		{Name: "RED", Value: 0},
+		{Name: "GREEN", Value: 4},
+		{Name: "BLUE", Value: 5},
+		{Name: "CRIMSON", Value: 0, IsAlias: true},
+		{Name: "BLACK", Value: 16},
+		{Name: "WHITE", Value: 17},
+	}
+
+	if len(enumerators) != len(expected) {
+		t.Errorf("wrong number of enumerators. expected %d but got %d", len(expected), len(enumerators))
+		return
+	}
+
+	for i, enumerator := range enumerators {
+		if enumerator.Name != expected[i].Name || enumerator.Value != expected[i].Value || enumerator.IsAlias != expected[i].IsAlias {
+			t.Errorf("got wrong enumerator. expected %+v but got %+v", expected[i], enumerator)
+		}
+	}
+
+}
+
+func TestWrite_EnumBackingType(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.EnumerationsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+	fidl.EnumBackingType = "UInt8"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ReceiverWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	if !strings.Contains(out.String(), "type Color uint8\n") {
+		t.Errorf("expected enumeration backed by uint8 in\n%s", out.String())
+	}
+
+	fidl.EnumBackingType = "Int8"
+	out.Reset()
+	if err := Write(fidl, ReceiverWriter, &out); err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for _, expected := range []string{"type Color int16\n", "ColorSignature = \"n\""} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q for enumeration backed by Int8 in\n%s", expected, out.String())
+		}
+	}
+
+	fidl.EnumBackingType = "String"
+	if err := Write(fidl, ReceiverWriter, &out); err == nil {
+		t.Error("expected error for non integer backing type")
+	}
+
+}
+
+func TestWrite_EnumeratorRange(t *testing.T) {
+
+	table := []struct {
+		backingType string
+		value       string
+		expectError bool
+	}{
+		{"", "2147483647", false},
+		{"", "5000000000", true},
+		{"", "-2147483648", false},
+		{"UInt8", "255", false},
+		{"UInt8", "256", true},
+		{"UInt16", "-1", true},
+		{"Int64", "5000000000", false},
+	}
+	for _, row := range table {
+
+		//given
+		fidl, err := NewParser(strings.NewReader(fmt.Sprintf(`package org.example
+interface Test {
+	enumeration Big { A = %s }
+}`, row.value))).Parse()
+		if err != nil {
+			t.Errorf("could not parse fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+		fidl.EnumBackingType = row.backingType
+
+		//when
+		err = Write(fidl, SenderWriter, &bytes.Buffer{})
+
+		//then
+		if row.expectError && (err == nil || !strings.Contains(err.Error(), "invalid value "+row.value+" for enumerator Big.A")) {
+			t.Errorf("expected range error for %s backed by %q but got %v", row.value, row.backingType, err)
+		}
+
+		if !row.expectError && err != nil {
+			t.Errorf("expected no error for %s backed by %q but got %v", row.value, row.backingType, err)
+		}
+
+	}
+
+}
+
+func TestWrite_Maps(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.MapsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for _, expected := range []string{
+		"// Options by name.\ntype Options map[string]uint32\n",
+		"type ScopedOptions map[string]Options\n",
+		"type OptionsList []Options\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, out.String())
+		}
+	}
+
+}
+
+func TestWrite_Unions(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.UnionsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for _, expected := range []string{
+		"type Value interface {\n\tisValue()\n}\n",
+		"type ValuePoint Point\n\nfunc (ValuePoint) isValue() {}\n",
+		"valueVariant, err := EncodeValue(value)\n",
+		"CallWithContext(ctx, \"org.example.Unions.Exchange\", 0, valueVariant, name)\n",
+		"DecodeValueSlice(call.Body[1], &history)",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, out.String())
+		}
+	}
+
+}
+
+func TestWrite_NestedUnions(t *testing.T) {
+
+	tests := []struct {
+		writer   WriterType
+		expected []string
+	}{
+		{SenderWriter, []string{
+			"func DecodeSample(body interface{}, value *Sample) error {",
+			"if err := DecodeValue(fields[1], &value.Value); err != nil {",
+			"if err := DecodeValueSlice(fields[2], &value.History); err != nil {",
+			"func DecodeSamplesByName(body interface{}, value *SamplesByName) error {",
+			"CallWithContext(ctx, \"org.example.Unions.Record\", 0, sample)",
+			"DecodeSampleSlice(call.Body[0], &samples)",
+			"DecodeSamplesByName(call.Body[1], &byName)",
+			"return DecodeSample(variant.Value(), value)",
+		}},
+		{ReceiverWriter, []string{
+			"func DecodeSample(body interface{}, value *Sample) error {",
+			"DecodeSample(sig.Body[0], &event.Sample)",
+		}},
+		{ServerWriter, []string{
+			"func DecodeSample(body interface{}, value *Sample) error {",
+			"func (impl *unionsServer) handleRecord(sample dbus.Variant) (",
+			"if err := DecodeSample(sample.Value(), &sampleValue); err != nil {",
+			"if err := DecodeValue(value, &valueValue); err != nil {",
+			"if err := DecodeSample(variant.Value(), &value); err != nil {",
+		}},
+	}
+
+	for _, tt := range tests {
+
+		//given
+		fidl, err := NewParser(bytes.NewReader(examples.UnionsFidl)).Parse()
+		if err != nil {
+			t.Errorf("could not parse fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+
+		//when
+		var out bytes.Buffer
+		err = Write(fidl, tt.writer, &out)
+
+		//then
+		if err != nil {
+			t.Errorf("could not write fidl because of: %v", err)
+			return
+		}
+
+		for _, expected := range tt.expected {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("expected %q in\n%s", expected, out.String())
+			}
+		}
+
+		// structs without unions are still decoded by dbus.Store
+		if strings.Contains(out.String(), "func DecodePoint(") {
+			t.Errorf("unexpected decode function for Point in\n%s", out.String())
+		}
+	}
+
+}
+
+func TestConstantValue(t *testing.T) {
+
+	//given
+	table := []struct {
+		constant      Constant
+		expectedValue string
+		expectError   bool
+	}{
+		{Constant{Type: "UInt8", Value: "0xFF"}, "0xFF", false},
+		{Constant{Type: "UInt8", Value: "256"}, "", true},
+		{Constant{Type: "UInt32", Value: "-1"}, "", true},
+		{Constant{Type: "Int8", Value: "-128"}, "-128", false},
+		{Constant{Type: "Double", Value: "2.5d"}, "2.5", false},
+		{Constant{Type: "Boolean", Value: "1"}, "", true},
+		{Constant{Type: "String", Value: `"text"`}, `"text"`, false},
+		{Constant{Type: "String", Value: "12"}, "", true},
+	}
+	for _, row := range table {
+
+		result, err := constantValue(row.constant)
+
+		if row.expectError && err == nil {
+			t.Errorf("expected error for %+v", row.constant)
+		}
+
+		if !row.expectError && result != row.expectedValue {
+			t.Errorf("got wrong value. expected %v but got %v (%v)", row.expectedValue, result, err)
+		}
+
+	}
+
+}
+
+func TestWrite_MultipleInterfaces(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.TypeCollectionsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ReceiverWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"type Entry struct {":                               1,
+		"type Level int32":                                  1,
+		"type Key = string":                                 1,
+		"func NewReaderReceiver(dest, path string)":         1,
+		"func NewWriterReceiver(dest, path string)":         1,
+		"CallWithContext(ctx, \"org.example.Writer.Write\"": 1,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_Imports(t *testing.T) {
+
+	//given
+	table := []struct {
+		goPackages map[string]string
+		expected   []string
+	}{
+		{nil, []string{"type Job struct {", "(Job, error)", "state JobState"}},
+		{map[string]string{"org.example.common": "example.com/common"}, []string{"\"example.com/common\"", "(common.Job, error)", "state common.JobState"}},
+		{map[string]string{"org.example.common": "shared=example.com/go-common"}, []string{"shared \"example.com/go-common\"", "(shared.Job, error)", "state shared.JobState"}},
+	}
+	for _, row := range table {
+
+		fidl, err := NewResolver().Load("../examples/Imports.fidl")
+		if err != nil {
+			t.Errorf("could not load fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+		fidl.GoPackages = row.goPackages
+
+		//when
+		var out bytes.Buffer
+		err = Write(fidl, SenderWriter, &out)
+
+		//then
+		if err != nil {
+			t.Errorf("could not write fidl because of: %v", err)
+			return
+		}
+
+		for _, expected := range row.expected {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("expected %q in\n%s", expected, out.String())
+			}
+		}
+
+	}
+
+}
+
+func TestWrite_ImportsInvalidPackageName(t *testing.T) {
+
+	//given
+	table := []struct {
+		goPackage string
+		expected  string
+	}{
+		{"example.com/go-common", "package name of example.com/go-common can't be derived from the import path, declare it like org.example.common=name=example.com/go-common"},
+		{"gopkg.in/common.v1", "package name of gopkg.in/common.v1 can't be derived from the import path, declare it like org.example.common=name=gopkg.in/common.v1"},
+		{"go-common=example.com/go-common", "invalid package name \"go-common\" for FIDL package org.example.common"},
+	}
+	for _, row := range table {
+
+		fidl, err := NewResolver().Load("../examples/Imports.fidl")
+		if err != nil {
+			t.Errorf("could not load fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+		fidl.GoPackages = map[string]string{"org.example.common": row.goPackage}
+
+		//when
+		err = Write(fidl, SenderWriter, &bytes.Buffer{})
+
+		//then
+		if err == nil || err.Error() != row.expected {
+			t.Errorf("wrong error for %s. expected %s but got %v", row.goPackage, row.expected, err)
+		}
+
+	}
+
+}
+
+func TestWrite_Inheritance(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.InheritanceFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"func (impl *printerSender) Reset(ctx context.Context) error": 1,
+		"func (impl *printerSender) GetName(ctx context.Context)":     1,
+		"func (impl *printerSender) SendFailedSignal(reason string)":  1,
+		"\"org.example.devices.Device.Reset\"":                        2,
+		"\"org.example.devices.Printer.Print\"":                       1,
+		"\"org.example.devices.Device\", \"Failed\"":                  2,
+		"\"org.example.devices.Printer.Reset\"":                       0,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_InheritanceErrors(t *testing.T) {
+
+	//given
+	table := []struct {
+		fidl          string
+		expectedError string
+	}{
+		{`package a
+interface A extends Unknown {
+}`, "interface A extends unknown interface Unknown"},
+		{`package a
+interface A extends B {
+}
+interface B extends A {
+}`, "extends itself"},
+		{`package a
+interface A {
+	method Do {
+	}
+}
+interface B extends A {
+	method Do {
+	}
+}`, "method Do of interface a.B is already declared by a.A"},
+	}
+	for _, row := range table {
+
+		fidl, err := NewParser(strings.NewReader(row.fidl)).Parse()
+		if err != nil {
+			t.Errorf("could not parse fidl because of: %v", err)
+			continue
+		}
+		fidl.TargetPackage = "test"
+
+		//when
+		err = Write(fidl, ReceiverWriter, &bytes.Buffer{})
+
+		//then
+		if err == nil || !strings.Contains(err.Error(), row.expectedError) {
+			t.Errorf("expected error %q but got %v", row.expectedError, err)
+		}
+
+	}
+
+}
+
+func TestWrite_MethodErrors(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.MethodErrorsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ReceiverWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"type MethodError struct":                                     1,
+		"= &MethodError{Name: \"org.example.Vault.Error.NoSuchKey\"}": 1,
+		"ErrVaultQuotaExceeded = &MethodError":                        1,
+		"return value, toMethodError(err)":                            1,
+		"return revision, toMethodError(err)":                         1,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_NoMethodErrors(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.FireAndForgetsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	if strings.Contains(out.String(), "MethodError") {
+		t.Errorf("expected no error declarations in\n%s", out.String())
+	}
+
+}
+
+func TestWrite_Server(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.InheritanceFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ServerWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"type PrinterHandler interface {":                                                                1,
+		"Print(ctx context.Context, document string) (uint32, error)":                                    1,
+		"func NewPrinterServer(name, path string, handler PrinterHandler, attributes PrinterAttributes)": 1,
+		"methods[\"org.example.devices.Device\"][\"Reset\"] = impl.handleReset":                          2,
+		"methods[\"org.example.devices.Printer\"][\"Print\"] = impl.handlePrint":                         1,
+		"\"GetAll\": impl.handlePropertiesGetAll,":                                                       2,
+		"conn.RequestName(name, dbus.NameFlagDoNotQueue)":                                                2,
+		"func toDBusError(err error) *dbus.Error":                                                        1,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_Introspection(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.UnionsFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ServerWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for _, expected := range []string{
+		"const unionsIntrospection = `<!DOCTYPE node",
+		"<interface name=\"org.example.Unions\">",
+		"<arg name=\"history\" type=\"av\" direction=\"out\"/>",
+		"<signal name=\"ValueChanged\">",
+		"<interface name=\"org.freedesktop.DBus.Introspectable\">",
+		"introspect.Introspectable(unionsIntrospection)",
+		"\"github.com/godbus/dbus/v5/introspect\"",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, out.String())
+		}
+	}
+
+}
+
+func TestWrite_AttributesAsProperties(t *testing.T) {
+
+	//given
+	table := []struct {
+		writerType WriterType
+		expected   []string
+	}{
+		{SenderWriter, []string{
+			"type UnionsAttributes struct {",
+			"GetAttributes(ctx context.Context) (UnionsAttributes, error)",
+			"CallWithContext(ctx, \"org.freedesktop.DBus.Properties.Get\", 0, \"org.example.Unions\", \"Current\")",
+			"CallWithContext(ctx, \"org.freedesktop.DBus.Properties.Set\", 0, \"org.example.Unions\", \"Recent\", variant)",
+			"CallWithContext(ctx, \"org.freedesktop.DBus.Properties.GetAll\", 0, iface)",
+		}},
+		{ServerWriter, []string{
+			"func NewUnionsServer(name, path string, handler UnionsHandler, attributes UnionsAttributes)",
+			"methods[\"org.freedesktop.DBus.Properties\"] = map[string]interface{}{",
+			"func (impl *unionsServer) SetCurrent(value Value) error {",
+			"<property name=\"Recent\" type=\"av\" access=\"readwrite\">",
+			"<interface name=\"org.freedesktop.DBus.Properties\">",
+		}},
+	}
+	for _, row := range table {
+
+		fidl, err := NewParser(bytes.NewReader(examples.UnionsFidl)).Parse()
+		if err != nil {
+			t.Errorf("could not parse fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+
+		//when
+		var out bytes.Buffer
+		err = Write(fidl, row.writerType, &out)
+
+		//then
+		if err != nil {
+			t.Errorf("could not write fidl because of: %v", err)
+			return
+		}
+
+		if strings.Contains(out.String(), "Attribute\"") {
+			t.Errorf("expected no attribute getter methods in\n%s", out.String())
+		}
+
+		for _, expected := range row.expected {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("expected %q in\n%s", expected, out.String())
+			}
+		}
+
+	}
+
+}
+
+func TestWrite_AttributeChanges(t *testing.T) {
+
+	//given
+	table := []struct {
+		writerType WriterType
+		expected   []string
+	}{
+		{SenderWriter, []string{
+			"WatchCurrent(ctx context.Context, options ...ListenOption) (<-chan Value, error)",
+			"WatchRecent(ctx context.Context, options ...ListenOption) (<-chan []Value, error)",
+			"dbus.WithMatchArg(0, \"org.example.Unions\")",
+			"fetched, err := impl.GetCurrent(ctx)",
+			"dbus.WithMatchSender(impl.destination),",
+			"subscriber := dispatcher.subscribe([]signalKey{",
+			"owner, err := nameOwner(ctx, impl.dbusConnection, impl.destination)",
+			"if owner == \"\" || sig.Sender != owner || len(sig.Body) != 3 {",
+		}},
+		{ServerWriter, []string{
+			"return impl.emitPropertiesChanged(\"org.example.Unions\", \"Current\", variant)",
+			"impl.attributesLock.Lock()\n\tdefer impl.attributesLock.Unlock()",
+			"\"org.freedesktop.DBus.Properties.PropertiesChanged\"",
+			"<annotation name=\"org.freedesktop.DBus.Property.EmitsChangedSignal\" value=\"true\"/>",
+		}},
+	}
+	for _, row := range table {
+
+		fidl, err := NewParser(bytes.NewReader(examples.UnionsFidl)).Parse()
+		if err != nil {
+			t.Errorf("could not parse fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+
+		//when
+		var out bytes.Buffer
+		err = Write(fidl, row.writerType, &out)
+
+		//then
+		if err != nil {
+			t.Errorf("could not write fidl because of: %v", err)
+			return
+		}
+
+		for _, expected := range row.expected {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("expected %q in\n%s", expected, out.String())
+			}
+		}
+
+	}
+
+}
+
+func TestWrite_AttributeModifiers(t *testing.T) {
+
+	//given
+	table := []struct {
+		writerType WriterType
+		expected   map[string]int
+	}{
+		{SenderWriter, map[string]int{
+			"SetName(ctx context.Context, value string) error":                                       4,
+			"SetSerialNumber(ctx context.Context, value string) error":                               0,
+			"WatchSerialNumber(ctx context.Context, options ...ListenOption) (<-chan string, error)": 4,
+			"GetQueuedJobs(ctx context.Context) (uint32, error)":                                     2,
+			"SetQueuedJobs(ctx context.Context, value uint32) error":                                 0,
+			"WatchQueuedJobs(ctx context.Context, options ...ListenOption) (<-chan uint32, error)":   0,
+		}},
+		{ServerWriter, map[string]int{
+			"\"org.freedesktop.DBus.Error.PropertyReadOnly\"":                                              3,
+			"return impl.emitPropertiesChanged(\"org.example.devices.Device\", \"serialNumber\", variant)": 2,
+			"return impl.emitPropertiesChanged(\"org.example.devices.Printer\", \"queuedJobs\", variant)":  0,
+			"<property name=\"serialNumber\" type=\"s\" access=\"read\">":                                  2,
+			"<property name=\"queuedJobs\" type=\"u\" access=\"read\">":                                    1,
+			"<annotation name=\"org.freedesktop.DBus.Property.EmitsChangedSignal\" value=\"false\"/>":      1,
+		}},
+	}
+	for _, row := range table {
+
+		fidl, err := NewParser(bytes.NewReader(examples.InheritanceFidl)).Parse()
+		if err != nil {
+			t.Errorf("could not parse fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+
+		//when
+		var out bytes.Buffer
+		err = Write(fidl, row.writerType, &out)
+
+		//then
+		if err != nil {
+			t.Errorf("could not write fidl because of: %v", err)
+			return
+		}
+
+		for expected, count := range row.expected {
+			if strings.Count(out.String(), expected) != count {
+				t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+			}
+		}
+
+	}
+
+}
+
+func TestWrite_BroadcastEvents(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.InheritanceFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ReceiverWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"type DeviceFailedEvent struct {\n\tReason string\n}\n":                                                            1,
+		"type PrinterFailedEvent struct {\n\tReason string\n}\n":                                                           1,
+		"type PrinterPrintedEvent struct {\n\tJobId uint32\n}\n":                                                           1,
+		"ListenForPrinted(ctx context.Context, options ...ListenOption) (<-chan PrinterPrintedEvent, <-chan error, error)": 2,
+		"if err := dbus.Store(sig.Body, &event.JobId); err != nil {":                                                       1,
+		"event, err := decodePrinterFailedEvent(sig)":                                                                      1,
+		"chan *dbus.Signal, error)":                                                                                        0,
+		"dbus.WithMatchMember(\"Printed\"),":                                                                               1,
+		"{dbus.ObjectPath(impl.path), \"org.example.devices.Device.Failed\"},":                                             2,
+		"{dbus.ObjectPath(impl.path), \"org.example.devices.Printer.Printed\"},":                                           1,
+		"strings.Contains(sig.Name":                                                                                        0,
+		"_ = impl.dbusConnection.RemoveMatchSignal(matchOptions...)":                                                       4,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_BroadcastOwner(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.InheritanceFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ReceiverWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"dbus.WithMatchSender(dest),":                                         4,
+		"nameOwnerMatchOptions(dest),":                                        4,
+		"dbus.WithMatchMember(\"NameOwnerChanged\"),":                         1,
+		"owner, err := nameOwner(ctx, impl.dbusConnection, impl.destination)": 4,
+		"owner, _ = sig.Body[2].(string)":                                     4,
+		"sig.Sender != owner":                                                 4,
+		"\"org.freedesktop.DBus.Error.NameHasNoOwner\"":                       1,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_SignalDispatcher(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.SystemManagerFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ReceiverWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for expected, count := range map[string]int{
+		"func signalDispatcherOf(conn *dbus.Conn) *signalDispatcher {": 1,
+		"conn.Signal(dispatcher.signals)":                              1,
+		"dispatcher := signalDispatcherOf(impl.dbusConnection)":        6,
+		"close(signalsChannel)":                                        0,
+		"func WithSignalPolicy(policy SignalPolicy) ListenOption {":    1,
+	} {
+		if strings.Count(out.String(), expected) != count {
+			t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+		}
+	}
+
+}
+
+func TestWrite_SignalDispatcherRouting(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.SystemManagerFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "receiver"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ReceiverWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	runGo(t, map[string][]byte{
+		"receiver/receiver.go":        out.Bytes(),
+		"receiver/dispatcher_test.go": []byte(dispatcherTest),
+	}, "test", "./...")
+
+}
+
+// dispatcherTest is run against the generated dispatcher by
+// TestWrite_SignalDispatcherRouting.
+const dispatcherTest = `package receiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func newTestDispatcher() *signalDispatcher {
+	return &signalDispatcher{subscribers: map[signalKey]map[*signalSubscriber]struct{}{}}
+}
+
+func received(subscriber *signalSubscriber) []*dbus.Signal {
+	var signals []*dbus.Signal
+	for {
+		select {
+		case sig := <-subscriber.signals:
+			signals = append(signals, sig)
+		default:
+			return signals
+		}
+	}
+}
+
+func TestDispatch_Routing(t *testing.T) {
+	dispatcher := newTestDispatcher()
+	jobNew := dispatcher.subscribe([]signalKey{{"/a", "org.example.Manager.JobNew"}})
+	otherPath := dispatcher.subscribe([]signalKey{{"/b", "org.example.Manager.JobNew"}})
+	otherMember := dispatcher.subscribe([]signalKey{{"/a", "org.example.Manager.JobRemoved"}})
+	otherInterface := dispatcher.subscribe([]signalKey{{"/a", "org.example.Other.JobNew"}})
+	both := dispatcher.subscribe([]signalKey{{"/a", "org.example.Manager.JobNew"}, {"/b", "org.example.Manager.JobNew"}})
+
+	dispatcher.dispatch(&dbus.Signal{Path: "/a", Name: "org.example.Manager.JobNew"})
+
+	for _, test := range []struct {
+		name       string
+		subscriber *signalSubscriber
+		expected   int
+	}{
+		{"jobNew", jobNew, 1},
+		{"otherPath", otherPath, 0},
+		{"otherMember", otherMember, 0},
+		{"otherInterface", otherInterface, 0},
+		{"both", both, 1},
+	} {
+		if got := len(received(test.subscriber)); got != test.expected {
+			t.Errorf("expected %d signals for %s but got %d", test.expected, test.name, got)
+		}
+	}
+}
+
+func TestDispatch_BufferSize(t *testing.T) {
+	dispatcher := newTestDispatcher()
+
+	if subscriber := dispatcher.subscribe(nil); cap(subscriber.signals) != 10 {
+		t.Errorf("expected default buffer of 10 but got %d", cap(subscriber.signals))
+	}
+	if subscriber := dispatcher.subscribe(nil, WithSignalBuffer(3)); cap(subscriber.signals) != 3 {
+		t.Errorf("expected buffer of 3 but got %d", cap(subscriber.signals))
+	}
+}
+
+func TestDispatch_SignalDrop(t *testing.T) {
+	dispatcher := newTestDispatcher()
+	key := signalKey{"/a", "org.example.Manager.JobNew"}
+	subscriber := dispatcher.subscribe([]signalKey{key}, WithSignalBuffer(1), WithSignalPolicy(SignalDrop))
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.dispatch(&dbus.Signal{Path: key.path, Name: key.name, Body: []interface{}{1}})
+		dispatcher.dispatch(&dbus.Signal{Path: key.path, Name: key.name, Body: []interface{}{2}})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch blocked although the policy is SignalDrop")
+	}
+
+	signals := received(subscriber)
+	if len(signals) != 1 || signals[0].Body[0] != 1 {
+		t.Errorf("expected only the first signal but got %v", signals)
+	}
+}
+
+func TestDispatch_SignalBlock(t *testing.T) {
+	dispatcher := newTestDispatcher()
+	key := signalKey{"/a", "org.example.Manager.JobNew"}
+	subscriber := dispatcher.subscribe([]signalKey{key}, WithSignalBuffer(1))
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.dispatch(&dbus.Signal{Path: key.path, Name: key.name, Body: []interface{}{1}})
+		dispatcher.dispatch(&dbus.Signal{Path: key.path, Name: key.name, Body: []interface{}{2}})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("dispatch didn't block although the buffer is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for _, expected := range []int{1, 2} {
+		if sig := <-subscriber.signals; sig.Body[0] != expected {
+			t.Errorf("expected signal %d but got %v", expected, sig.Body[0])
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch still blocked after the signals were read")
+	}
+}
+
+func TestDispatch_UnsubscribeUnblocks(t *testing.T) {
+	dispatcher := newTestDispatcher()
+	key := signalKey{"/a", "org.example.Manager.JobNew"}
+	subscriber := dispatcher.subscribe([]signalKey{key}, WithSignalBuffer(0))
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.dispatch(&dbus.Signal{Path: key.path, Name: key.name})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("dispatch didn't block although nobody reads")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	dispatcher.unsubscribe(subscriber)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch still blocked after unsubscribe")
+	}
+
+	dispatcher.dispatch(&dbus.Signal{Path: key.path, Name: key.name})
+	if len(dispatcher.subscribers) != 0 {
+		t.Errorf("expected no subscribers but got %v", dispatcher.subscribers)
+	}
+}
+`
+
+func TestWrite_SelectiveBroadcasts(t *testing.T) {
+
+	//given
+	table := []struct {
+		writerType WriterType
+		expected   map[string]int
+	}{
+		{ServerWriter, map[string]int{
+			"methods[\"org.example.devices.Printer\"][\"subscribeForJobProgressSelective\"] = impl.subscribeForJobProgress":       1,
+			"methods[\"org.example.devices.Printer\"][\"unsubscribeFromJobProgressSelective\"] = impl.unsubscribeFromJobProgress": 1,
+			"BroadcastJobProgress(jobId uint32, percent uint8) error":                                                             2,
+			"SendJobProgressSignal(target string, jobId uint32, percent uint8) error":                                             2,
+			"if err := impl.watchSubscribers(); err != nil {":                                                                     1,
+			"<method name=\"subscribeForJobProgressSelective\"/>":                                                                 1,
+			"func emitWithDestination(": 1,
+		}},
+		{ReceiverWriter, map[string]int{
+			"CallWithContext(ctx, \"org.example.devices.Printer.subscribeForJobProgressSelective\", 0)":              1,
+			"Go(\"org.example.devices.Printer.subscribeForJobProgressSelective\", dbus.FlagNoReplyExpected, nil)":    1,
+			"Go(\"org.example.devices.Printer.unsubscribeFromJobProgressSelective\", dbus.FlagNoReplyExpected, nil)": 1,
+		}},
+		{SenderWriter, map[string]int{
+			"SendJobProgressSignal(target string, jobId uint32, percent uint8) error": 2,
+			"func emitWithDestination(": 1,
+		}},
+	}
+	for _, row := range table {
+
+		fidl, err := NewParser(bytes.NewReader(examples.InheritanceFidl)).Parse()
+		if err != nil {
+			t.Errorf("could not parse fidl because of: %v", err)
+			return
+		}
+		fidl.TargetPackage = "test"
+
+		//when
+		var out bytes.Buffer
+		err = Write(fidl, row.writerType, &out)
+
+		//then
+		if err != nil {
+			t.Errorf("could not write fidl because of: %v", err)
+			return
+		}
+
+		for expected, count := range row.expected {
+			if strings.Count(out.String(), expected) != count {
+				t.Errorf("expected %q %d times in\n%s", expected, count, out.String())
+			}
+		}
+
+	}
+
+}
+
+func TestWrite_SelectiveSubscriptionsPerListener(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.InheritanceFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "server"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, ServerWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	runGo(t, map[string][]byte{
+		"server/server.go":           out.Bytes(),
+		"server/subscribers_test.go": []byte(subscribersTest),
+	}, "test", "./...")
+
+}
+
+// subscribersTest is run against the generated server by
+// TestWrite_SelectiveSubscriptionsPerListener. Every listener of a client
+// subscribes on its own, so the client stays subscribed until the last one
+// unsubscribed.
+const subscribersTest = `package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubscriptions_TwoListeners(t *testing.T) {
+	impl := &printerServer{subscribers: map[string]map[string]int{}}
+	broadcast := "org.example.devices.Printer.JobProgress"
+
+	impl.subscribeForJobProgress(":1.42")
+	impl.subscribeForJobProgress(":1.42")
+	impl.subscribeForJobProgress(":1.43")
+
+	impl.unsubscribeFromJobProgress(":1.42")
+	impl.unsubscribeFromJobProgress(":1.43")
+	if subscribers := impl.subscribersOf(broadcast); !reflect.DeepEqual(subscribers, []string{":1.42"}) {
+		t.Errorf("expected :1.42 to stay subscribed for its second listener but got %v", subscribers)
+	}
+
+	impl.unsubscribeFromJobProgress(":1.42")
+	impl.unsubscribeFromJobProgress(":1.42")
+	if subscribers := impl.subscribersOf(broadcast); len(subscribers) != 0 {
+		t.Errorf("expected no subscribers but got %v", subscribers)
+	}
+
+	impl.subscribeForJobProgress(":1.42")
+	if subscribers := impl.subscribersOf(broadcast); !reflect.DeepEqual(subscribers, []string{":1.42"}) {
+		t.Errorf("expected :1.42 to be subscribed again but got %v", subscribers)
+	}
+}
+`
+
+func TestWrite_TypeSignatures(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.SystemManagerFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "test"
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	for _, expected := range []string{
+		"GetUnit(ctx context.Context, name string) (dbus.ObjectPath, error)",
+		"StartupTimestamp(ctx context.Context) (uint64, error)",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, out.String())
+		}
+	}
+
+	for _, expected := range []string{
+		`JobInfoSignature\s+= "\(usssoo\)"`,
+		`FileListSignature\s+= "as"`,
+	} {
+		if !regexp.MustCompile(expected).MatchString(out.String()) {
+			t.Errorf("expected %q in\n%s", expected, out.String())
+		}
+	}
+
+}
+
+func TestWrite_AccessorCollisions(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		fidl     string
+		expected []string
+		err      string
+	}{
+		{
+			name: "renamed accessors",
+			fidl: "package test\ninterface Manager {\n\tattribute String[] Environment\n\tattribute UInt32 Jobs\n\tmethod SetEnvironment {\n\t\tin {\n\t\t\tString[] names\n\t\t}\n\t}\n}",
+			expected: []string{
+				"SetEnvironment(ctx context.Context, names []string) error",
+				"GetEnvironmentAttribute(ctx context.Context) ([]string, error)",
+				"SetEnvironmentAttribute(ctx context.Context, value []string) error",
+				"WatchEnvironmentAttribute(ctx context.Context, options ...ListenOption) (<-chan []string, error)",
+				"GetJobs(ctx context.Context) (uint32, error)",
+			},
+		},
+		{
+			name: "renamed accessor collides",
+			fidl: "package test\ninterface Manager {\n\tattribute UInt32 Jobs\n\tmethod GetJobs { }\n\tmethod WatchJobsAttribute { }\n}",
+			err:  "attribute Jobs of interface test.Manager collides with method WatchJobsAttribute of interface test.Manager, both are generated as WatchJobsAttribute",
+		},
+		{
+			name: "generated method",
+			fidl: "package test\ninterface Manager {\n\tmethod Close { }\n}",
+			err:  "method Close of interface test.Manager collides with the generated method Close, both are generated as Close",
+		},
+		{
+			name: "broadcast",
+			fidl: "package test\ninterface Manager {\n\tmethod ListenForChanged { }\n\tbroadcast Changed { }\n}",
+			err:  "broadcast Changed of interface test.Manager collides with method ListenForChanged of interface test.Manager, both are generated as ListenForChanged",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			//given
+			fidl, err := NewParser(strings.NewReader(test.fidl)).Parse()
+			if err != nil {
+				t.Errorf("could not parse fidl because of: %v", err)
+				return
+			}
+			fidl.TargetPackage = "test"
+
+			//when
+			var out bytes.Buffer
+			err = Write(fidl, SenderWriter, &out)
+
+			//then
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected error %q but got %v", test.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("could not write fidl because of: %v", err)
+				return
+			}
+
+			for _, expected := range test.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expected %q in\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+
+}
+
+func TestWrite_NotificationExampleUpToDate(t *testing.T) {
+
+	//given
+	fidl, err := NewParser(bytes.NewReader(examples.NotificationFidl)).Parse()
+	if err != nil {
+		t.Errorf("could not parse fidl because of: %v", err)
+		return
+	}
+	fidl.TargetPackage = "notification"
+
+	typeMap, err := os.Open("../examples/notification/types.map")
+	if err != nil {
+		t.Fatalf("could not open type map because of: %v", err)
+	}
+	defer typeMap.Close()
+
+	fidl.TypeMappings, err = ReadTypeMappings(typeMap)
+	if err != nil {
+		t.Fatalf("could not read type map because of: %v", err)
+	}
+
+	expected, err := os.ReadFile("../examples/notification/NotificationSender.go")
+	if err != nil {
+		t.Fatalf("could not read generated example because of: %v", err)
+	}
+
+	//when
+	var out bytes.Buffer
+	err = Write(fidl, SenderWriter, &out)
+
+	//then
+	if err != nil {
+		t.Errorf("could not write fidl because of: %v", err)
+		return
+	}
+
+	if out.String() != string(expected) {
+		t.Errorf("examples/notification/NotificationSender.go is outdated, run go generate in examples/notification/cmd")
+	}
+
+}
+
+func TestWrite_Compiles(t *testing.T) {
+
+	//given
+	sources := map[string][]byte{
+		"systemd": examples.SystemManagerFidl,
+		"unions":  examples.UnionsFidl,
+		"errors":  examples.MethodErrorsFidl,
+		"collision": []byte("package test\ninterface Manager {\n\tattribute String[] Environment\n" +
+			"\tmethod SetEnvironment {\n\t\tin {\n\t\t\tString[] names\n\t\t}\n\t}\n}"),
+		"nestedunions": []byte("package test\ninterface Tree {\n\tunion Leaf {\n\t\tString text\n\t\tUInt32 number\n\t}\n" +
+			"\tunion Node {\n\t\tLeaf[] leaves\n\t\tString name\n\t}\n" +
+			"\tattribute Node root\n\tmethod Replace {\n\t\tin {\n\t\t\tNode node\n\t\t}\n\t\tout {\n\t\t\tNode[] old\n\t\t}\n\t}\n" +
+			"\tbroadcast Replaced {\n\t\tout {\n\t\t\tNode node\n\t\t}\n\t}\n}"),
+	}
+	writers := map[string]WriterType{"sender": SenderWriter, "receiver": ReceiverWriter, "server": ServerWriter}
+
+	//when
+	files := map[string][]byte{}
+	for name, source := range sources {
+		fidl, err := NewParser(bytes.NewReader(source)).Parse()
+		if err != nil {
+			t.Errorf("could not parse %s because of: %v", name, err)
+			return
+		}
+
+		for writerName, writer := range writers {
+			fidl.TargetPackage = writerName
+			var out bytes.Buffer
+			if err := Write(fidl, writer, &out); err != nil {
+				t.Errorf("could not write %s because of: %v", name, err)
+				return
+			}
+			files[fmt.Sprintf("%s/%s/%s.go", name, writerName, writerName)] = out.Bytes()
+		}
+	}
+
+	//then
+	runGo(t, files, "vet", "./...")
+
+}
+
+// runGo writes files into a temporary module depending on godbus and runs
+// the go tool with args in it. The test is skipped if the go tool or godbus
+// isn't available.
+func runGo(t *testing.T, files map[string][]byte, args ...string) {
+	t.Helper()
+
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available")
+	}
+
+	dir := t.TempDir()
+	files["go.mod"] = []byte("module generated\n\ngo 1.18\n\nrequire github.com/godbus/dbus/v5 v5.1.0\n")
+	files["go.sum"], err = os.ReadFile("../examples/notification/go.sum")
+	if err != nil {
+		t.Fatalf("could not read go.sum because of: %v", err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("could not create directory because of: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatalf("could not write %s because of: %v", name, err)
+		}
+	}
+
+	download := exec.Command(goTool, "mod", "download", "github.com/godbus/dbus/v5")
+	download.Dir = dir
+	if out, err := download.CombinedOutput(); err != nil {
+		t.Skipf("godbus not available: %s", out)
+	}
+
+	cmd := exec.Command(goTool, args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go %s failed because of: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}